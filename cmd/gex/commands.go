@@ -0,0 +1,660 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+
+	"github.com/izumin5210/gex"
+	"github.com/izumin5210/gex/pkg/importer"
+	"github.com/izumin5210/gex/pkg/manager"
+	"github.com/izumin5210/gex/pkg/tool"
+)
+
+// command is a subcommand of gex, such as `gex add`.
+type command struct {
+	Name string
+	// Usage is a synopsis of arguments and flags of the command.
+	Usage string
+	Short string
+	// Flags defines flags of the command. Variables are shared with the legacy flags such as `--group`.
+	Flags func(fs *pflag.FlagSet)
+	// Passthrough stops parsing flags at the first argument, so that flags after it are passed to the tool.
+	Passthrough bool
+	// DisableFlagParsing passes all arguments to Run as they are.
+	DisableFlagParsing bool
+	// JSON reports tools in the JSON document with `--format json`. Other commands write their output as is.
+	JSON   bool
+	Hidden bool
+	Run    func(ctx context.Context, a *app, args []string) error
+	// Complete returns candidates for the positional argument.
+	Complete func(ctx context.Context, a *app, args []string, cur string) []string
+
+	// local contains flags of the command, and flags contains them and global flags.
+	local, flags *pflag.FlagSet
+}
+
+func (c *command) init() {
+	c.local = pflag.NewFlagSet(cliName+" "+c.Name, pflag.ContinueOnError)
+	if c.Flags != nil {
+		c.Flags(c.local)
+	}
+	c.flags = pflag.NewFlagSet(cliName+" "+c.Name, pflag.ContinueOnError)
+	c.flags.SetOutput(ioutil.Discard)
+	c.flags.SetInterspersed(!c.Passthrough)
+	c.flags.AddFlagSet(c.local)
+	c.flags.AddFlagSet(globalFlags)
+}
+
+// parse parses flags of the command, and returns positional arguments.
+func (c *command) parse(args []string) ([]string, error) {
+	if c.DisableFlagParsing {
+		return args, nil
+	}
+	if err := c.flags.Parse(args); err != nil {
+		return nil, errors.Errorf("%v\nRun '%s help %s' for usage.", err, cliName, c.Name)
+	}
+	return c.flags.Args(), nil
+}
+
+var (
+	cmdInit = &command{
+		Name:  "init",
+		Usage: "init",
+		Short: "Initialize the manifest with gex itself",
+		Run: func(ctx context.Context, a *app, args []string) error {
+			toolRepo, err := a.repository()
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			return errors.WithStack(toolRepo.Add(ctx, "github.com/izumin5210/gex/cmd/gex"))
+		},
+	}
+
+	cmdAdd = &command{
+		Name:  "add",
+		Usage: "add <packages...>",
+		Short: "Add new tool dependencies, and build them",
+		JSON:  true,
+		Run: func(ctx context.Context, a *app, args []string) error {
+			if len(args) == 0 {
+				return errors.New("no packages are specified")
+			}
+			toolRepo, err := a.repository()
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			err = toolRepo.Add(ctx, args...)
+			if err == nil {
+				tools := make([]tool.Tool, len(args))
+				for i, pkg := range args {
+					tools[i] = tool.Tool(strings.SplitN(pkg, "@", 2)[0])
+				}
+				hintShadowedTools(os.Stderr, tools)
+			}
+			return output.report(ctx, toolRepo, a.rec, nil, args, err)
+		},
+		Complete: func(ctx context.Context, a *app, args []string, cur string) []string {
+			return packageCandidates(ctx, &a.cfg, cur)
+		},
+	}
+
+	cmdBuild = &command{
+		Name:  "build",
+		Usage: "build [--group name] [--timings [--trace file]]",
+		Short: "Build tools in parallel",
+		Flags: func(fs *pflag.FlagSet) {
+			defineGroupFlag(fs)
+			defineTimingsFlags(fs)
+		},
+		JSON: true,
+		Run:  runBuild,
+	}
+
+	cmdList = &command{
+		Name:  "list",
+		Usage: "list [--group name]",
+		Short: "List tools",
+		Flags: defineGroupFlag,
+		JSON:  true,
+		Run: func(ctx context.Context, a *app, args []string) error {
+			toolRepo, err := a.repository()
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			if output != nil {
+				return output.report(ctx, toolRepo, nil, flagGroups, nil, nil)
+			}
+			return listTools(ctx, toolRepo, flagGroups)
+		},
+	}
+
+	cmdRun = &command{
+		Name:        "run",
+		Usage:       "run <tool> [--] [args...]",
+		Short:       "Execute a tool, building it if needed",
+		Passthrough: true,
+		Run: func(ctx context.Context, a *app, args []string) error {
+			if len(args) == 0 {
+				return errors.New("no tool is specified")
+			}
+			toolArgs := args[1:]
+			if len(toolArgs) > 0 && toolArgs[0] == "--" {
+				toolArgs = toolArgs[1:]
+			}
+			toolRepo, err := a.repository()
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			return errors.WithStack(toolRepo.Run(ctx, args[0], toolArgs...))
+		},
+		Complete: func(ctx context.Context, a *app, args []string, cur string) []string {
+			if len(args) > 0 {
+				return nil
+			}
+			return toolCandidates(ctx, &a.cfg)
+		},
+	}
+
+	cmdGenerate = &command{
+		Name:  "generate",
+		Usage: "generate [packages...]",
+		Short: "Build tools used in go:generate directives, and run go generate",
+		Run: func(ctx context.Context, a *app, args []string) error {
+			toolRepo, err := a.repository()
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			err = toolRepo.Generate(ctx, args...)
+			if errs := asBuildErrors(err); errs != nil {
+				for _, err := range errs.Errs {
+					fmt.Fprintln(os.Stdout, err.Error())
+				}
+				return errBuildFailed
+			}
+			return errors.WithStack(err)
+		},
+	}
+
+	cmdDownload = &command{
+		Name:  "download",
+		Usage: "download [--group name]",
+		Short: "Download sources to build tools",
+		Flags: defineGroupFlag,
+		Run: func(ctx context.Context, a *app, args []string) error {
+			toolRepo, err := a.repository()
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			return errors.WithStack(toolRepo.Download(ctx, flagGroups...))
+		},
+	}
+
+	cmdRegen = &command{
+		Name:  "regen",
+		Usage: "regen",
+		Short: "Regenerate the manifest",
+		Run: func(ctx context.Context, a *app, args []string) error {
+			if _, err := a.repository(); err != nil {
+				return errors.WithStack(err)
+			}
+			cfg := &a.cfg
+			path := filepath.Join(cfg.RootDir, cfg.ManifestName)
+			m, err := tool.NewParser(cfg.FS, cfg.ManagerType).Parse(path)
+			if err != nil {
+				return errors.Wrapf(err, "%s was not found", path)
+			}
+			return errors.WithStack(tool.NewWriter(cfg.FS).Write(path, m))
+		},
+	}
+
+	cmdScan = &command{
+		Name:  "scan",
+		Usage: "scan [--fix]",
+		Short: "Check tools used in go:generate directives against the manifest",
+		Flags: func(fs *pflag.FlagSet) {
+			fs.BoolVar(&flagFix, "fix", false, "Add tools invoked with `go run` and remove unused tools")
+		},
+		Run: func(ctx context.Context, a *app, args []string) error {
+			toolRepo, err := a.repository()
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			return scan(ctx, toolRepo, a.cfg.WorkingDir, flagFix)
+		},
+	}
+
+	cmdShims = &command{
+		Name:  "shims",
+		Usage: "shims",
+		Short: "Write shims that build tools on first use into the bin directory",
+		Run: func(ctx context.Context, a *app, args []string) error {
+			toolRepo, err := a.repository()
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			return errors.WithStack(toolRepo.WriteShims(ctx))
+		},
+	}
+
+	cmdEnv = &command{
+		Name:  "env",
+		Usage: "env [bash|zsh|fish]",
+		Short: "Print commands to add the bin directory to PATH",
+		Run: func(ctx context.Context, a *app, args []string) error {
+			if _, err := a.repository(); err != nil {
+				return errors.WithStack(err)
+			}
+			shell, err := optionalArg(args)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			return printEnv(os.Stdout, &a.cfg, shell)
+		},
+		Complete: completeShells,
+	}
+
+	cmdShell = &command{
+		Name:  "shell",
+		Usage: "shell [bash|zsh|fish]",
+		Short: "Start a subshell that has the bin directory in PATH",
+		Run: func(ctx context.Context, a *app, args []string) error {
+			if _, err := a.repository(); err != nil {
+				return errors.WithStack(err)
+			}
+			shell, err := optionalArg(args)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			return runShell(ctx, &a.cfg, shell)
+		},
+		Complete: completeShells,
+	}
+
+	cmdMigrate = &command{
+		Name:  "migrate",
+		Usage: "migrate mod",
+		Short: "Migrate tools to another dependencies management tool (only dep to mod is supported)",
+		Run: func(ctx context.Context, a *app, args []string) error {
+			if len(args) == 0 {
+				return errors.New("no dependencies management tool is specified")
+			}
+			if _, err := a.repository(); err != nil {
+				return errors.WithStack(err)
+			}
+			return migrate(ctx, &a.cfg, args[0])
+		},
+		Complete: func(ctx context.Context, a *app, args []string, cur string) []string {
+			return []string{manager.TypeModules.String()}
+		},
+	}
+
+	cmdExport = &command{
+		Name:  "export",
+		Usage: "export <file>",
+		Short: "Export built tools into a bundle file",
+		Run: func(ctx context.Context, a *app, args []string) error {
+			if len(args) == 0 {
+				return errors.New("no bundle file is specified")
+			}
+			toolRepo, err := a.repository()
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			return exportBundle(ctx, toolRepo, args[0])
+		},
+	}
+
+	cmdImport = &command{
+		Name:  "import",
+		Usage: "import <file>",
+		Short: "Install tools from a bundle file",
+		Run: func(ctx context.Context, a *app, args []string) error {
+			if len(args) == 0 {
+				return errors.New("no bundle file is specified")
+			}
+			toolRepo, err := a.repository()
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			return importBundle(ctx, toolRepo, args[0])
+		},
+	}
+
+	cmdExportScript = &command{
+		Name:  "export-script",
+		Usage: "export-script [sh|make]",
+		Short: "Print a script that builds tools without gex",
+		Run: func(ctx context.Context, a *app, args []string) error {
+			toolRepo, err := a.repository()
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			return exportScript(ctx, toolRepo, string(tool.ScriptShell), args)
+		},
+		Complete: func(ctx context.Context, a *app, args []string, cur string) []string {
+			return []string{string(tool.ScriptShell), string(tool.ScriptMake)}
+		},
+	}
+
+	cmdImportFrom = &command{
+		Name:  "import-from",
+		Usage: "import-from <format> [path]",
+		Short: "Add tools declared for another tool manager (" + strings.Join(importer.Formats(), ", ") + ")",
+		Run: func(ctx context.Context, a *app, args []string) error {
+			if len(args) == 0 {
+				return errors.New("no format is specified")
+			}
+			toolRepo, err := a.repository()
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			return importFrom(ctx, toolRepo, &a.cfg, args[0], args[1:])
+		},
+		Complete: func(ctx context.Context, a *app, args []string, cur string) []string {
+			if len(args) > 0 {
+				return nil
+			}
+			return importer.Formats()
+		},
+	}
+
+	cmdCompletion = &command{
+		Name:  "completion",
+		Usage: "completion [bash|zsh|fish]",
+		Short: "Print a completion script for the shell",
+		Run: func(ctx context.Context, a *app, args []string) error {
+			shell, err := optionalArg(args)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			return printCompletion(os.Stdout, shell)
+		},
+		Complete: completeShells,
+	}
+
+	cmdComplete = &command{
+		Name: completeCommand,
+		// arguments are words on the command line, that may be incomplete flags
+		DisableFlagParsing: true,
+		Hidden:             true,
+		Run: func(ctx context.Context, a *app, args []string) error {
+			return complete(ctx, os.Stdout, a, args)
+		},
+	}
+
+	cmdVersion = &command{
+		Name:  "version",
+		Usage: "version",
+		Short: "Print the CLI version",
+		Run: func(ctx context.Context, a *app, args []string) error {
+			fmt.Fprintf(os.Stdout, "%s %s\n", cliName, gex.Version)
+			return nil
+		},
+	}
+
+	cmdHelp = &command{
+		Name:  "help",
+		Usage: "help [command]",
+		Short: "Print help for the CLI or the command",
+		Run: func(ctx context.Context, a *app, args []string) error {
+			if len(args) == 0 {
+				printHelp(os.Stdout)
+				return nil
+			}
+			c, ok := lookupCommand(args[0])
+			if !ok {
+				return errors.Errorf("unknown command %q", args[0])
+			}
+			printCommandHelp(os.Stdout, c)
+			return nil
+		},
+		Complete: func(ctx context.Context, a *app, args []string, cur string) []string {
+			if len(args) > 0 {
+				return nil
+			}
+			return commandCandidates()
+		},
+	}
+)
+
+// commands are listed in the help in this order. It is initialized in init() since commands refer to it.
+var commands []*command
+
+func lookupCommand(name string) (*command, bool) {
+	for _, c := range commands {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+func listTools(ctx context.Context, toolRepo tool.Repository, groups []string) error {
+	tools, err := toolRepo.List(ctx, groups...)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for _, t := range tools {
+		fmt.Fprintln(os.Stdout, t)
+	}
+	hintShadowedTools(os.Stderr, tools)
+	return nil
+}
+
+// hintShadowedTools writes hints for tools that have the same names as commands,
+// since `gex <name>` runs the command instead of the tool.
+func hintShadowedTools(w io.Writer, tools []tool.Tool) {
+	for _, t := range tools {
+		if _, ok := lookupCommand(t.Name()); ok {
+			fmt.Fprintf(w, "gex: hint: %s is shadowed by the %s command, run it with `gex run %s`\n", t, t.Name(), t.Name())
+		}
+	}
+}
+
+// optionalArg returns the argument of a command that takes at most one argument.
+func optionalArg(args []string) (string, error) {
+	switch len(args) {
+	case 0:
+		return "", nil
+	case 1:
+		return args[0], nil
+	}
+	return "", errors.Errorf("too many arguments: %s", strings.Join(args[1:], " "))
+}
+
+func defineGroupFlag(fs *pflag.FlagSet) {
+	fs.StringSliceVar(&flagGroups, "group", []string{}, "Select tools in the groups")
+}
+
+func defineTimingsFlags(fs *pflag.FlagSet) {
+	fs.BoolVar(&flagTimings, "timings", false, "Print build times of tools")
+	fs.StringVar(&flagTrace, "trace", "", "Write a trace of builds, that can be opened with chrome://tracing or Perfetto")
+}
+
+func runBuild(ctx context.Context, a *app, args []string) error {
+	// output of `go build` is shown only for tools that failed to build
+	var progress *progressRenderer
+	if output != nil {
+		progress = newProgressRenderer(os.Stderr, os.Stderr, false)
+	} else {
+		progress = newProgressRenderer(os.Stdout, os.Stderr, isTerminal(os.Stdout))
+	}
+	a.observe(progress)
+	a.logFilter = progress.filterLog
+	a.cfg.CaptureBuildOutput = true
+
+	var timings *timingRecorder
+	if flagTimings || flagTrace != "" {
+		timings = newTimingRecorder()
+		a.observe(timings)
+		a.cfg.BuildFlags = []string{"-x"}
+		progress.trace = true
+	}
+
+	toolRepo, err := a.repository()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	tools, err := toolRepo.List(ctx, flagGroups...)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	progress.Start(tools)
+	err = toolRepo.BuildAll(ctx, flagGroups...)
+	progress.Stop()
+	if timings != nil {
+		if terr := reportTimings(timings, flagTrace); terr != nil && err == nil {
+			err = terr
+		}
+	}
+	if errs := asBuildErrors(err); errs != nil {
+		if output == nil {
+			for _, err := range errs.Errs {
+				fmt.Fprintln(os.Stdout, err.Error())
+			}
+		}
+		err = errBuildFailed
+	}
+	return output.report(ctx, toolRepo, a.rec, flagGroups, nil, err)
+}
+
+func reportTimings(timings *timingRecorder, tracePath string) error {
+	w := io.Writer(os.Stdout)
+	if output != nil {
+		w = os.Stderr
+	}
+	timings.printSummary(w)
+
+	if tracePath == "" {
+		return nil
+	}
+	f, err := os.Create(tracePath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = timings.writeTrace(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return errors.Wrapf(err, "failed to write a trace into %s", tracePath)
+}
+
+// app holds the configuration shared by commands.
+type app struct {
+	cfg       gex.Config
+	observers []tool.Observer
+	// rec records results of builds for the JSON document. It is nil if the output format is text.
+	rec *buildRecorder
+	// logFilter wraps the observer that writes events into the logger if non-nil.
+	logFilter func(tool.Observer) tool.Observer
+}
+
+func newApp() (*app, error) {
+	a := new(app)
+	a.cfg.Offline = flagOffline
+	a.cfg.GexPath = executablePath()
+	if flagManager != "" {
+		var err error
+		a.cfg.ManagerType, err = manager.ParseType(flagManager)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	if flagVerbose {
+		a.cfg.Verbose = true
+		a.cfg.Logger = log.New(os.Stderr, "", 0)
+	}
+	if output != nil {
+		// stdout is reserved for the JSON document
+		a.cfg.OutWriter = os.Stderr
+		a.rec = newBuildRecorder()
+		a.observe(a.rec)
+	}
+	return a, nil
+}
+
+// executablePath returns an absolute path of the running gex.
+// It returns an empty string for a binary built by `go run`, since it is removed on exit.
+func executablePath() string {
+	exe, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return ""
+	}
+	if strings.Contains(filepath.ToSlash(exe), "/go-build") {
+		return ""
+	}
+	return exe
+}
+
+func (a *app) observe(o tool.Observer) {
+	a.observers = append(a.observers, o)
+}
+
+// repository creates a tool.Repository that sends events to the observers.
+func (a *app) repository() (tool.Repository, error) {
+	if len(a.observers) > 0 {
+		logObserver := tool.NewLogObserver(a.cfg.Logger)
+		if a.logFilter != nil {
+			logObserver = a.logFilter(logObserver)
+		}
+		a.cfg.Observer = tool.MultiObserver(append(a.observers, logObserver)...)
+	}
+	toolRepo, err := a.cfg.Create()
+	return toolRepo, errors.WithStack(err)
+}
+
+func printHelp(w io.Writer) {
+	fmt.Fprintln(w, helpText)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, c := range commands {
+		if !c.Hidden {
+			fmt.Fprintf(w, "  %-14s %s\n", c.Name, c.Short)
+		}
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Global Flags:")
+	fmt.Fprint(w, globalFlags.FlagUsages())
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Flags such as --add, --build and --list are also supported as aliases of the commands.")
+	fmt.Fprintf(w, "Run '%s help <command>' for more information about a command.\n", cliName)
+}
+
+func printCommandHelp(w io.Writer, c *command) {
+	fmt.Fprintln(w, c.Short)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Usage:")
+	fmt.Fprintf(w, "  %s %s\n", cliName, c.Usage)
+	if c.local.HasFlags() {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Flags:")
+		fmt.Fprint(w, c.local.FlagUsages())
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Global Flags:")
+	fmt.Fprint(w, globalFlags.FlagUsages())
+}
+
+var (
+	helpText = `The implementation of clarify best practice for tool dependencies.
+
+See https://github.com/golang/go/issues/25922#issuecomment-412992431
+
+Usage:
+  gex <command> [flags] [args...]
+  gex <tool> [args...]      Execute a tool (same as ` + "`gex run <tool>`" + `)
+  go generate ./tools.go    Build tools without gex`
+)
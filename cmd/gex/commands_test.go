@@ -0,0 +1,21 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/izumin5210/gex/pkg/tool"
+)
+
+func TestHintShadowedTools(t *testing.T) {
+	buf := new(bytes.Buffer)
+	hintShadowedTools(buf, []tool.Tool{
+		"github.com/golang/mock/mockgen",
+		"example.com/tools/cmd/build",
+	})
+
+	want := "gex: hint: example.com/tools/cmd/build is shadowed by the build command, run it with `gex run build`\n"
+	if got := buf.String(); got != want {
+		t.Errorf("hintShadowedTools() wrote %q, want %q", got, want)
+	}
+}
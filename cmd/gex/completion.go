@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+
+	"github.com/izumin5210/gex"
+	"github.com/izumin5210/gex/pkg/importer"
+	"github.com/izumin5210/gex/pkg/manager"
+	"github.com/izumin5210/gex/pkg/tool"
+)
+
+// completeCommand is a hidden command called from completion scripts as `gex __complete [args...] <current word>`.
+// It prints candidates for the current word.
+const completeCommand = "__complete"
+
+// fileFlags are flags that take file paths. Their values are completed by shells.
+var fileFlags = []string{"export", "import", "trace"}
+
+// fileCommands are commands that take file paths as arguments.
+var fileCommands = []string{"export", "import", "import-from"}
+
+// printCompletion prints a script that registers the completion for the shell.
+func printCompletion(w io.Writer, shell string) error {
+	switch filepath.Base(resolveShell(shell)) {
+	case "bash":
+		fmt.Fprint(w, bashCompletion)
+	case "zsh":
+		fmt.Fprint(w, zshCompletion)
+	case "fish":
+		fmt.Fprint(w, fishCompletion)
+		for _, f := range fileFlags {
+			fmt.Fprintf(w, "complete -c %s -l %s -r -F\n", cliName, f)
+		}
+		fmt.Fprintf(w, "complete -c %s -n '__fish_seen_subcommand_from %s' -F\n", cliName, strings.Join(fileCommands, " "))
+	default:
+		return errors.Errorf("unsupported shell: %s", shell)
+	}
+	return nil
+}
+
+// complete prints candidates for the last word of args.
+func complete(ctx context.Context, w io.Writer, a *app, args []string) error {
+	if len(args) == 0 {
+		args = []string{""}
+	}
+	cur, words := args[len(args)-1], args[:len(args)-1]
+
+	// flags before the command, including the legacy flags
+	fs := pflag.CommandLine
+	pos := positionals(fs, words)
+	var cmd *command
+	if len(pos) > 0 {
+		if c, ok := lookupCommand(words[pos[0]]); ok {
+			cmd, fs, words = c, c.flags, words[pos[0]+1:]
+			pos = positionals(fs, words)
+		}
+	}
+
+	var candidates []string
+	switch flag, ok := flagTakingValue(fs, words); {
+	case ok:
+		candidates = flagValueCandidates(ctx, &a.cfg, flag, cur)
+	case strings.HasPrefix(cur, "-"):
+		candidates = flagCandidates(fs)
+	case cmd != nil && cmd.Complete != nil:
+		args := make([]string, len(pos))
+		for i, p := range pos {
+			args[i] = words[p]
+		}
+		candidates = cmd.Complete(ctx, a, args, cur)
+	case cmd == nil && len(pos) == 0:
+		candidates = append(commandCandidates(), toolCandidates(ctx, &a.cfg)...)
+	}
+
+	for _, c := range candidates {
+		if strings.HasPrefix(c, cur) {
+			fmt.Fprintln(w, c)
+		}
+	}
+	return nil
+}
+
+// lookupFlag returns a flag named in the word, such as "--add" and "-v".
+func lookupFlag(fs *pflag.FlagSet, word string) *pflag.Flag {
+	switch {
+	case strings.HasPrefix(word, "--"):
+		return fs.Lookup(strings.TrimPrefix(word, "--"))
+	case strings.HasPrefix(word, "-") && len(word) == 2:
+		return fs.ShorthandLookup(word[1:])
+	}
+	return nil
+}
+
+// takesValue returns true if the flag consumes the next argument as its value.
+// Flags with optional values such as `--shell` are included, since gex reads the next argument as their value.
+func takesValue(f *pflag.Flag) bool {
+	return f != nil && f.Value.Type() != "bool"
+}
+
+// flagTakingValue returns a name of the flag if the last word is a flag that requires a value.
+func flagTakingValue(fs *pflag.FlagSet, words []string) (string, bool) {
+	if len(words) == 0 {
+		return "", false
+	}
+	f := lookupFlag(fs, words[len(words)-1])
+	if !takesValue(f) {
+		return "", false
+	}
+	return f.Name, true
+}
+
+// positionals returns indices of positional arguments in the words.
+func positionals(fs *pflag.FlagSet, words []string) []int {
+	var pos []int
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+		if !strings.HasPrefix(w, "-") {
+			pos = append(pos, i)
+			continue
+		}
+		if !strings.Contains(w, "=") && takesValue(lookupFlag(fs, w)) {
+			i++
+		}
+	}
+	return pos
+}
+
+func flagCandidates(fs *pflag.FlagSet) []string {
+	var candidates []string
+	fs.VisitAll(func(f *pflag.Flag) {
+		if f.Hidden {
+			return
+		}
+		candidates = append(candidates, "--"+f.Name)
+		if f.Shorthand != "" {
+			candidates = append(candidates, "-"+f.Shorthand)
+		}
+	})
+	return candidates
+}
+
+func commandCandidates() []string {
+	var candidates []string
+	for _, c := range commands {
+		if !c.Hidden {
+			candidates = append(candidates, c.Name)
+		}
+	}
+	return candidates
+}
+
+func completeShells(ctx context.Context, a *app, args []string, cur string) []string {
+	if len(args) > 0 {
+		return nil
+	}
+	return []string{"bash", "zsh", "fish"}
+}
+
+func flagValueCandidates(ctx context.Context, cfg *gex.Config, flag, cur string) []string {
+	switch flag {
+	case "add":
+		return packageCandidates(ctx, cfg, cur)
+	case "group":
+		return groupCandidates(ctx, cfg)
+	case "format":
+		return []string{formatText, formatJSON}
+	case "manager":
+		return []string{"mod", "dep", "gopath"}
+	case "migrate-to":
+		return []string{"mod"}
+	case "import-from":
+		return importer.Formats()
+	case "export-script":
+		return []string{string(tool.ScriptShell), string(tool.ScriptMake)}
+	}
+	return nil
+}
+
+func toolCandidates(ctx context.Context, cfg *gex.Config) []string {
+	toolRepo, err := cfg.Create()
+	if err != nil {
+		return nil
+	}
+	tools, err := toolRepo.List(ctx)
+	if err != nil {
+		return nil
+	}
+	candidates := make([]string, len(tools))
+	for i, t := range tools {
+		candidates[i] = t.Name()
+	}
+	return candidates
+}
+
+func groupCandidates(ctx context.Context, cfg *gex.Config) []string {
+	toolRepo, err := cfg.Create()
+	if err != nil {
+		return nil
+	}
+	statuses, err := toolRepo.Status(ctx)
+	if err != nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var candidates []string
+	for _, st := range statuses {
+		for _, g := range st.Groups {
+			if !seen[g] {
+				seen[g] = true
+				candidates = append(candidates, g)
+			}
+		}
+	}
+	sort.Strings(candidates)
+	return candidates
+}
+
+const mainPackageFormat = `{{if eq .Name "main"}}{{.ImportPath}}{{end}}`
+
+// packageCandidates returns modules required by the project and main packages in the project.
+// Main packages in the module are also returned if cur is in the module.
+func packageCandidates(ctx context.Context, cfg *gex.Config, cur string) []string {
+	if _, err := cfg.Create(); err != nil {
+		return nil
+	}
+
+	var candidates []string
+	if cfg.ManagerType == manager.TypeModules {
+		for _, mod := range goList(ctx, cfg, "-m", "-f", "{{if not .Main}}{{.Path}}{{end}}", "all") {
+			candidates = append(candidates, mod)
+			if cur == mod || strings.HasPrefix(cur, mod+"/") {
+				candidates = append(candidates, goList(ctx, cfg, "-f", mainPackageFormat, mod+"/...")...)
+			}
+		}
+	}
+	candidates = append(candidates, goList(ctx, cfg, "-f", mainPackageFormat, "./...")...)
+	return candidates
+}
+
+func goList(ctx context.Context, cfg *gex.Config, args ...string) []string {
+	cmd := cfg.Exec.CommandContext(ctx, "go", append([]string{"list", "-e"}, args...)...)
+	cmd.Dir = cfg.WorkingDir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+	var lines []string
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		if l := strings.TrimSpace(s.Text()); l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+var (
+	bashCompletion = `# bash completion for gex
+_gex() {
+	local IFS=$'\n'
+	COMPREPLY=($(gex ` + completeCommand + ` "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F _gex gex
+`
+
+	zshCompletion = `#compdef gex
+# zsh completion for gex
+_gex() {
+	local -a candidates
+	candidates=(${(f)"$(gex ` + completeCommand + ` "${(@)words[2,$CURRENT]}" 2>/dev/null)"})
+	if (( ${#candidates} == 0 )); then
+		_files
+		return
+	fi
+	compadd -a candidates
+}
+compdef _gex gex
+`
+
+	fishCompletion = `# fish completion for gex
+function __gex_complete
+	set -l args (commandline -opc) (commandline -ct)
+	gex ` + completeCommand + ` $args[2..-1] 2>/dev/null
+end
+complete -c gex -f -a '(__gex_complete)'
+`
+)
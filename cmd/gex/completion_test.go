@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/bradleyjkemp/cupaloy/v2"
+	"github.com/google/go-cmp/cmp"
+	"github.com/izumin5210/execx"
+	"github.com/spf13/afero"
+
+	"github.com/izumin5210/gex"
+	"github.com/izumin5210/gex/pkg/manager"
+	"github.com/izumin5210/gex/pkg/tool"
+)
+
+const completionRootDir = "/home/src/awesomeapp"
+
+// newCompletionApp creates an app for the module that manages mockgen and golint.
+// `go list` prints modules required by the module, and main packages in them.
+func newCompletionApp(t *testing.T, managerType manager.Type) *app {
+	t.Helper()
+
+	fs := afero.NewMemMapFs()
+	m := tool.NewManifest([]tool.Tool{
+		"github.com/golang/mock/mockgen",
+		"golang.org/x/lint/golint",
+	}, managerType)
+	m.SetGroups("github.com/golang/mock/mockgen", "codegen")
+	m.SetGroups("golang.org/x/lint/golint", "lint", "ci")
+	err := tool.NewWriter(fs).Write(completionRootDir+"/tools.go", m)
+	if err != nil {
+		t.Fatalf("failed to write the manifest: %v", err)
+	}
+
+	outputs := map[string]string{
+		"-m -f {{if not .Main}}{{.Path}}{{end}} all":              "github.com/golang/mock\ngolang.org/x/lint\n",
+		"-f " + mainPackageFormat + " github.com/golang/mock/...": "github.com/golang/mock/mockgen\n",
+		"-f " + mainPackageFormat + " ./...":                      "awesomeapp/cmd/awesomeapp\n",
+	}
+	fakeExec := execx.New(execx.WithFakeProcess(func(_ context.Context, cmd *exec.Cmd) error {
+		if len(cmd.Args) > 3 && cmd.Args[1] == "list" {
+			fmt.Fprint(cmd.Stdout, outputs[strings.Join(cmd.Args[3:], " ")])
+		}
+		return nil
+	}))
+
+	return &app{cfg: gex.Config{
+		FS:          fs,
+		Exec:        fakeExec,
+		WorkingDir:  completionRootDir,
+		RootDir:     completionRootDir,
+		ManagerType: managerType,
+		Logger:      log.New(ioutil.Discard, "", 0),
+	}}
+}
+
+func TestComplete(t *testing.T) {
+	cases := []struct {
+		test string
+		args []string
+		want []string
+	}{
+		{
+			test: "commands and tools",
+			args: []string{"m"},
+			want: []string{"migrate", "mockgen"},
+		},
+		{
+			test: "no args",
+			args: nil,
+			want: append(commandCandidates(), "mockgen", "golint"),
+		},
+		{
+			test: "global flags",
+			args: []string{"--fo"},
+			want: []string{"--format"},
+		},
+		{
+			test: "flags of the command",
+			args: []string{"build", "--t"},
+			want: []string{"--timings", "--trace"},
+		},
+		{
+			test: "flag values",
+			args: []string{"--format", ""},
+			want: []string{"text", "json"},
+		},
+		{
+			test: "groups",
+			args: []string{"build", "--group", ""},
+			want: []string{"ci", "codegen", "lint"},
+		},
+		{
+			test: "groups after other flags",
+			args: []string{"list", "-v", "--group", "c"},
+			want: []string{"ci", "codegen"},
+		},
+		{
+			test: "modules",
+			args: []string{"add", "g"},
+			want: []string{"github.com/golang/mock", "golang.org/x/lint"},
+		},
+		{
+			test: "packages in the module",
+			args: []string{"add", "github.com/golang/mock/"},
+			want: []string{"github.com/golang/mock/mockgen"},
+		},
+		{
+			test: "packages with the legacy flag",
+			args: []string{"--add", "github.com/golang/mock/m"},
+			want: []string{"github.com/golang/mock/mockgen"},
+		},
+		{
+			test: "shells",
+			args: []string{"completion", ""},
+			want: []string{"bash", "zsh", "fish"},
+		},
+		{
+			test: "too many args",
+			args: []string{"completion", "bash", ""},
+			want: []string{},
+		},
+		{
+			test: "files",
+			args: []string{"export", ""},
+			want: []string{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := complete(context.Background(), &buf, newCompletionApp(t, manager.TypeModules), tc.args)
+			if err != nil {
+				t.Fatalf("complete() returned an error: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, strings.Fields(buf.String())); diff != "" {
+				t.Errorf("complete() printed unexpected candidates: (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCompleteCommand(t *testing.T) {
+	cases := []struct {
+		test string
+		args []string
+		want []string
+	}{
+		{
+			test: "incomplete flag",
+			args: []string{"--ver"},
+			want: []string{"--verbose"},
+		},
+		{
+			test: "empty value of the legacy flag",
+			args: []string{"--add", ""},
+			want: []string{"github.com/golang/mock", "golang.org/x/lint", "awesomeapp/cmd/awesomeapp"},
+		},
+		{
+			test: "packages with the legacy flag",
+			args: []string{"--add", "github.com/golang/mock/"},
+			want: []string{"github.com/golang/mock/mockgen"},
+		},
+		{
+			test: "incomplete flag value",
+			args: []string{"--format", "j"},
+			want: []string{"json"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			// `gex __complete [args...]` parses the arguments with the command
+			args, err := cmdComplete.parse(tc.args)
+			if err != nil {
+				t.Fatalf("parse() returned an error: %v", err)
+			}
+			var buf bytes.Buffer
+			err = complete(context.Background(), &buf, newCompletionApp(t, manager.TypeModules), args)
+			if err != nil {
+				t.Fatalf("complete() returned an error: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, strings.Fields(buf.String())); diff != "" {
+				t.Errorf("complete() printed unexpected candidates: (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPackageCandidates(t *testing.T) {
+	cases := []struct {
+		test        string
+		managerType manager.Type
+		cur         string
+		want        []string
+	}{
+		{
+			test:        "modules",
+			managerType: manager.TypeModules,
+			cur:         "",
+			want:        []string{"github.com/golang/mock", "golang.org/x/lint", "awesomeapp/cmd/awesomeapp"},
+		},
+		{
+			test:        "in the module",
+			managerType: manager.TypeModules,
+			cur:         "github.com/golang/mock/mo",
+			want:        []string{"github.com/golang/mock", "github.com/golang/mock/mockgen", "golang.org/x/lint", "awesomeapp/cmd/awesomeapp"},
+		},
+		{
+			test:        "GOPATH",
+			managerType: manager.TypeGOPATH,
+			cur:         "github.com/golang/mock/mo",
+			want:        []string{"awesomeapp/cmd/awesomeapp"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			a := newCompletionApp(t, tc.managerType)
+			got := packageCandidates(context.Background(), &a.cfg, tc.cur)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("packageCandidates() returned unexpected candidates: (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPrintCompletion(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		t.Run(shell, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := printCompletion(&buf, shell)
+			if err != nil {
+				t.Fatalf("printCompletion() returned an error: %v", err)
+			}
+			cupaloy.SnapshotT(t, buf.String())
+		})
+	}
+
+	t.Run("unsupported", func(t *testing.T) {
+		err := printCompletion(ioutil.Discard, "tcsh")
+		if err == nil {
+			t.Error("printCompletion() should return an error for tcsh")
+		}
+	})
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/izumin5210/gex"
+	"github.com/izumin5210/gex/pkg/manager"
+	"github.com/izumin5210/gex/pkg/tool"
+)
+
+// printEnv prints commands to export environment variables for the shell.
+func printEnv(w io.Writer, cfg *gex.Config, shell string) error {
+	path := manager.PrependPath(os.Getenv("PATH"), cfg.BinDir())
+
+	switch filepath.Base(resolveShell(shell)) {
+	case "bash", "zsh", "sh":
+		fmt.Fprintf(w, "export PATH=%s\n", tool.ShellQuote(path))
+	case "fish":
+		entries := filepath.SplitList(path)
+		for i, e := range entries {
+			entries[i] = tool.ShellQuote(e)
+		}
+		fmt.Fprintf(w, "set -gx PATH %s\n", strings.Join(entries, " "))
+	default:
+		return errors.Errorf("unsupported shell: %s", shell)
+	}
+
+	return nil
+}
+
+// runShell starts a subshell that has the bin directory in its PATH.
+func runShell(ctx context.Context, cfg *gex.Config, shell string) error {
+	cmd := cfg.Exec.CommandContext(ctx, resolveShell(shell))
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Dir = cfg.WorkingDir
+	cmd.Env = cfg.Environ()
+	err := cmd.Run()
+	if _, ok := errors.Cause(err).(*exec.ExitError); ok {
+		// the exit status of the subshell is not an error of gex
+		return nil
+	}
+	return errors.WithStack(err)
+}
+
+// resolveShell returns the given shell, or the user's login shell if it is not specified.
+func resolveShell(shell string) string {
+	if shell == "" {
+		shell = os.Getenv("SHELL")
+	}
+	if shell == "" {
+		return "sh"
+	}
+	return shell
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/izumin5210/execx"
+	"github.com/spf13/afero"
+
+	"github.com/izumin5210/gex"
+	"github.com/izumin5210/gex/pkg/manager"
+)
+
+func TestPrintEnv(t *testing.T) {
+	rootDir := filepath.FromSlash("/home/src/awesomeapp")
+	binDir := filepath.Join(rootDir, "bin")
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", filepath.FromSlash("/home/user's/bin")+string(os.PathListSeparator)+binDir)
+
+	cases := []struct {
+		shell   string
+		want    string
+		wantErr bool
+	}{
+		{
+			shell: "bash",
+			want:  "export PATH='" + binDir + string(os.PathListSeparator) + filepath.FromSlash("/home/user'\\''s/bin") + "'\n",
+		},
+		{
+			shell: "/bin/zsh",
+			want:  "export PATH='" + binDir + string(os.PathListSeparator) + filepath.FromSlash("/home/user'\\''s/bin") + "'\n",
+		},
+		{
+			shell: "fish",
+			want:  "set -gx PATH '" + binDir + "' '" + filepath.FromSlash("/home/user'\\''s/bin") + "'\n",
+		},
+		{
+			shell:   "tcsh",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.shell, func(t *testing.T) {
+			cfg := &gex.Config{
+				FS:          afero.NewMemMapFs(),
+				WorkingDir:  rootDir,
+				RootDir:     rootDir,
+				ManagerType: manager.TypeModules,
+			}
+			var buf bytes.Buffer
+			err := printEnv(&buf, cfg, tc.shell)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("printEnv() should return an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("printEnv() returned an error: %v", err)
+			}
+			if got, want := buf.String(), tc.want; got != want {
+				t.Errorf("printEnv() printed %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestRunShell(t *testing.T) {
+	rootDir := filepath.FromSlash("/home/src/awesomeapp")
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", filepath.FromSlash("/usr/bin"))
+
+	var (
+		args []string
+		env  = make(map[string]string)
+	)
+	cfg := &gex.Config{
+		FS:          afero.NewMemMapFs(),
+		WorkingDir:  rootDir,
+		RootDir:     rootDir,
+		ManagerType: manager.TypeModules,
+		Offline:     true,
+		Env:         []string{"FOO=bar"},
+		Exec: execx.New(execx.WithFakeProcess(func(_ context.Context, cmd *exec.Cmd) error {
+			args = cmd.Args
+			for _, kv := range cmd.Env {
+				if kv := strings.SplitN(kv, "=", 2); len(kv) == 2 {
+					env[kv[0]] = kv[1]
+				}
+			}
+			return nil
+		})),
+	}
+
+	err := runShell(context.Background(), cfg, "zsh")
+	if err != nil {
+		t.Fatalf("runShell() returned an error: %v", err)
+	}
+
+	if got, want := args[0], "zsh"; got != want {
+		t.Errorf("runShell() started %s, want %s", got, want)
+	}
+	want := map[string]string{
+		"PATH":    filepath.Join(rootDir, "bin") + string(os.PathListSeparator) + filepath.FromSlash("/usr/bin"),
+		"GOPROXY": "off",
+		"FOO":     "bar",
+	}
+	for k, v := range want {
+		if got := env[k]; got != v {
+			t.Errorf("%s of the subshell is %q, want %q", k, got, v)
+		}
+	}
+}
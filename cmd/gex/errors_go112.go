@@ -1,14 +1,27 @@
+//go:build !go1.13
 // +build !go1.13
 
 package main
 
 import (
+	"github.com/pkg/errors"
+
 	"github.com/izumin5210/gex/pkg/tool"
 )
 
 func asBuildErrors(err error) *tool.BuildErrors {
-	if errs, ok := err.(*tool.BuildErrors); ok {
+	if errs, ok := errors.Cause(err).(*tool.BuildErrors); ok {
 		return errs
 	}
 	return nil
 }
+
+// isError reports whether the cause of err matches target, like errors.Is since Go 1.13.
+func isError(err, target error) bool {
+	cause := errors.Cause(err)
+	if cause == target {
+		return true
+	}
+	x, ok := cause.(interface{ Is(error) bool })
+	return ok && x.Is(target)
+}
@@ -1,3 +1,4 @@
+//go:build go1.13
 // +build go1.13
 
 package main
@@ -15,3 +16,7 @@ func asBuildErrors(err error) *tool.BuildErrors {
 	}
 	return nil
 }
+
+func isError(err, target error) bool {
+	return errors.Is(err, target)
+}
@@ -3,12 +3,13 @@ package main
 import (
 	"context"
 	"fmt"
-	"io"
-	"log"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/izumin5210/gex"
+	"github.com/izumin5210/gex/pkg/importer"
+	"github.com/izumin5210/gex/pkg/manager"
 	"github.com/izumin5210/gex/pkg/tool"
 	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
@@ -18,106 +19,405 @@ const (
 	cliName = "gex"
 )
 
+// Exit codes
+const (
+	exitCodeOK = iota
+	exitCodeError
+	exitCodeBuildFailed
+	exitCodeToolNotFound
+	exitCodeManifestNotFound
+	exitCodeManagerUnknown
+)
+
+var errBuildFailed = errors.New("failed to build tools")
+
 var (
-	pkgsToBeAdded []string
-	flagBuild     bool
-	flagInit      bool
-	flagRegen     bool
-	flagVersion   bool
-	flagVerbose   bool
-	flagHelp      bool
+	// legacy flags that are aliases of commands, e.g. `gex --add` is `gex add`
+	pkgsToBeAdded    []string
+	flagExport       string
+	flagImport       string
+	flagImportFrom   string
+	flagExportScript string
+	flagBuild        bool
+	flagList         bool
+	flagInit         bool
+	flagRegen        bool
+	flagDownload     bool
+	flagScan         bool
+	flagGenerate     bool
+	flagShims        bool
+	flagEnv          bool
+	flagCompletion   bool
+	flagMigrateTo    string
+	flagVersion      bool
+
+	// flags of commands, that are also accepted with the legacy flags
+	flagGroups  []string
+	flagTimings bool
+	flagTrace   string
+	flagShell   bool
+	flagFix     bool
+
+	// global flags
+	flagManager string
+	flagOffline bool
+	flagFormat  string
+	flagVerbose bool
+	flagHelp    bool
 )
 
+// globalFlags are accepted both before and after commands.
+var globalFlags = pflag.NewFlagSet(cliName, pflag.ContinueOnError)
+
+// output is a document written with `--format json` for commands that report tools. It is nil if the format is text.
+var output *jsonDocument
+
 func init() {
+	globalFlags.StringVar(&flagManager, "manager", "", "Dependencies management tool (mod, dep or gopath). Detected automatically if not specified")
+	globalFlags.BoolVar(&flagOffline, "offline", false, "Build tools without network access")
+	globalFlags.StringVar(&flagFormat, "format", formatText, "Output format of list, build, add and errors (text or json)")
+	globalFlags.BoolVarP(&flagVerbose, "verbose", "v", false, "Verbose level output")
+	globalFlags.BoolVarP(&flagHelp, "help", "h", false, "Help for the CLI or the command")
+
 	pflag.SetInterspersed(false)
+	pflag.CommandLine.AddFlagSet(globalFlags)
 	pflag.StringArrayVar(&pkgsToBeAdded, "add", []string{}, "Add new tools")
 	pflag.BoolVar(&flagInit, "init", false, "Initialize tools manifest")
 	pflag.BoolVar(&flagBuild, "build", false, "Build all tools")
+	pflag.BoolVar(&flagList, "list", false, "List tools")
+	defineGroupFlag(pflag.CommandLine)
+	defineTimingsFlags(pflag.CommandLine)
 	pflag.BoolVar(&flagRegen, "regen", false, "Regenerate manifest")
+	pflag.BoolVar(&flagGenerate, "generate", false, "Build tools used in go:generate directives and run go generate")
+	pflag.BoolVar(&flagShims, "shims", false, "Write shims that build tools on first use into the bin directory")
+	pflag.BoolVar(&flagEnv, "env", false, "Print commands to add the bin directory to PATH")
+	pflag.BoolVar(&flagShell, "shell", false, "Start a subshell that has the bin directory in PATH")
+	pflag.BoolVar(&flagCompletion, "completion", false, "Print a completion script for the shell")
+	pflag.BoolVar(&flagDownload, "download", false, "Download sources to build tools")
+	pflag.BoolVar(&flagScan, "scan", false, "Report tools used in go:generate directives but missing from manifest, and vice versa")
+	pflag.BoolVar(&flagFix, "fix", false, "Fix the manifest with --scan")
+	pflag.StringVar(&flagMigrateTo, "migrate-to", "", "Migrate tools to another dependencies management tool (only dep to mod is supported)")
+	pflag.StringVar(&flagExport, "export", "", "Export built tools into a bundle file")
+	pflag.StringVar(&flagImport, "import", "", "Install tools from a bundle file")
+	pflag.StringVar(&flagExportScript, "export-script", "", "Print a script (sh or make) that builds tools without gex")
+	pflag.Lookup("export-script").NoOptDefVal = string(tool.ScriptShell)
+	pflag.StringVar(&flagImportFrom, "import-from", "", "Add tools declared for another tool manager ("+strings.Join(importer.Formats(), ", ")+")")
 	pflag.BoolVar(&flagVersion, "version", false, "Print the CLI version")
-	pflag.BoolVarP(&flagVerbose, "verbose", "v", false, "Verbose level output")
-	pflag.BoolVarP(&flagHelp, "help", "h", false, "Help for the CLI")
+	// the legacy flags are not shown in the help, but they keep working
+	pflag.VisitAll(func(f *pflag.Flag) {
+		if globalFlags.Lookup(f.Name) == nil {
+			f.Hidden = true
+		}
+	})
+
+	commands = []*command{
+		cmdInit,
+		cmdAdd,
+		cmdBuild,
+		cmdList,
+		cmdRun,
+		cmdGenerate,
+		cmdDownload,
+		cmdRegen,
+		cmdScan,
+		cmdShims,
+		cmdEnv,
+		cmdShell,
+		cmdMigrate,
+		cmdExport,
+		cmdImport,
+		cmdExportScript,
+		cmdImportFrom,
+		cmdCompletion,
+		cmdVersion,
+		cmdHelp,
+		cmdComplete,
+	}
+	for _, c := range commands {
+		c.init()
+	}
 }
 
 func main() {
 	var exitCode int
 
-	if err := run(); err != nil {
+	err := run()
+	if err != nil {
+		exitCode = exitCodeOf(err)
+	}
+
+	if output == nil && err != nil && flagFormat == formatJSON {
+		// errors of other commands are reported in the JSON document too
+		output = newJSONDocument()
+	}
+	if output != nil {
+		output.setError(exitCode, err)
+		if werr := output.write(os.Stdout); werr != nil {
+			fmt.Fprintln(os.Stderr, werr)
+			exitCode = exitCodeError
+		}
+	} else if err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		exitCode = 1
 	}
 
 	os.Exit(exitCode)
 }
 
+func exitCodeOf(err error) int {
+	switch {
+	case err == nil:
+		return exitCodeOK
+	case isError(err, errBuildFailed), asBuildErrors(err) != nil:
+		return exitCodeBuildFailed
+	case isError(err, tool.ErrToolNotFound):
+		return exitCodeToolNotFound
+	case isError(err, tool.ErrManifestNotFound):
+		return exitCodeManifestNotFound
+	case isError(err, tool.ErrManagerUnknown):
+		return exitCodeManagerUnknown
+	default:
+		return exitCodeError
+	}
+}
+
 func run() error {
 	pflag.Parse()
-	args := pflag.Args()
 
-	var cfg gex.Config
-	if flagVerbose {
-		cfg.Verbose = true
-		cfg.Logger = log.New(os.Stderr, "", 0)
+	cmd, args, err := resolveCommand(pflag.Args())
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if flagHelp && cmd != cmdHelp {
+		printCommandHelp(os.Stdout, cmd)
+		return nil
 	}
-	toolRepo, err := cfg.Create()
+
+	switch flagFormat {
+	case formatText:
+	case formatJSON:
+		if cmd.JSON {
+			output = newJSONDocument()
+		}
+	default:
+		return errors.Errorf("unknown output format %q, text or json is supported", flagFormat)
+	}
+
+	a, err := newApp()
 	if err != nil {
 		return errors.WithStack(err)
 	}
 
-	ctx := context.TODO()
+	return errors.WithStack(cmd.Run(context.TODO(), a, args))
+}
 
+// resolveCommand returns the command to run and its arguments.
+// The legacy flags such as `--add` are aliases of commands, and `gex <tool>` is an alias of `gex run <tool>`.
+func resolveCommand(args []string) (*command, []string, error) {
 	switch {
 	case len(pkgsToBeAdded) > 0:
-		err = toolRepo.Add(ctx, pkgsToBeAdded...)
+		return cmdAdd, pkgsToBeAdded, nil
 	case flagVersion:
-		fmt.Fprintf(os.Stdout, "%s %s\n", cliName, gex.Version)
+		return cmdVersion, nil, nil
 	case flagHelp:
-		printHelp(os.Stdout)
+		return cmdHelp, args, nil
 	case flagBuild:
-		err = toolRepo.BuildAll(ctx)
-		if errs := asBuildErrors(err); errs != nil {
-			for _, err := range errs.Errs {
-				fmt.Fprintln(os.Stdout, err.Error())
-			}
-			return errors.New("failed to build tools")
-		}
-		return err
+		return cmdBuild, nil, nil
+	case flagDownload:
+		return cmdDownload, nil, nil
+	case flagList:
+		return cmdList, nil, nil
+	case flagGenerate:
+		return cmdGenerate, args, nil
+	case flagEnv:
+		return cmdEnv, args, nil
+	case flagShell:
+		return cmdShell, args, nil
+	case flagMigrateTo != "":
+		return cmdMigrate, []string{flagMigrateTo}, nil
+	case flagShims:
+		return cmdShims, nil, nil
+	case flagScan:
+		return cmdScan, nil, nil
 	case flagInit:
-		err = toolRepo.Add(ctx, "github.com/izumin5210/gex/cmd/gex")
+		return cmdInit, nil, nil
 	case flagRegen:
-		path := filepath.Join(cfg.RootDir, cfg.ManifestName)
-		m, err := tool.NewParser(cfg.FS, cfg.ManagerType).Parse(path)
+		return cmdRegen, nil, nil
+	case flagExport != "":
+		return cmdExport, []string{flagExport}, nil
+	case flagImport != "":
+		return cmdImport, []string{flagImport}, nil
+	case flagExportScript != "":
+		return cmdExportScript, append([]string{flagExportScript}, args...), nil
+	case flagImportFrom != "":
+		return cmdImportFrom, append([]string{flagImportFrom}, args...), nil
+	case flagCompletion:
+		return cmdCompletion, args, nil
+	case len(args) == 0:
+		return cmdHelp, nil, nil
+	}
+
+	if c, ok := lookupCommand(args[0]); ok {
+		args, err := c.parse(args[1:])
+		return c, args, errors.WithStack(err)
+	}
+	return cmdRun, args, nil
+}
+
+func scan(ctx context.Context, toolRepo tool.Repository, wd string, fix bool) error {
+	report, err := toolRepo.Scan(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	var added []string
+	for _, ref := range report.Missing {
+		pos := ref.Pos
+		if rel, err := filepath.Rel(wd, pos); err == nil {
+			pos = rel
+		}
+		switch {
+		case ref.Package == "":
+			fmt.Fprintf(os.Stdout, "%s: %s is not managed in the manifest\n", pos, ref.Name)
+		case fix:
+			added = append(added, ref.Package)
+		default:
+			fmt.Fprintf(os.Stdout, "%s: %s is not managed in the manifest\n", pos, ref.Package)
+		}
+	}
+
+	removed := make([]string, 0, len(report.Unused))
+	for _, t := range report.Unused {
+		if fix {
+			removed = append(removed, string(t))
+		} else {
+			fmt.Fprintf(os.Stdout, "%s is never used in go:generate directives\n", t)
+		}
+	}
+
+	if !fix {
+		if !report.Empty() {
+			return errors.Errorf("found %d missing and %d unused tool(s)", len(report.Missing), len(report.Unused))
+		}
+		return nil
+	}
+
+	if len(removed) > 0 {
+		err = toolRepo.Remove(ctx, removed...)
 		if err != nil {
-			return errors.Wrapf(err, "%s was not found", path)
+			return errors.WithStack(err)
 		}
-		err = tool.NewWriter(cfg.FS).Write(path, m)
+		fmt.Fprintf(os.Stdout, "removed %s\n", strings.Join(removed, ", "))
+	}
+	if len(added) > 0 {
+		err = toolRepo.Add(ctx, added...)
 		if err != nil {
 			return errors.WithStack(err)
 		}
-	case len(args) > 0:
-		err = toolRepo.Run(ctx, args[0], args[1:]...)
-	default:
-		printHelp(os.Stdout)
+		fmt.Fprintf(os.Stdout, "added %s\n", strings.Join(added, ", "))
+	}
+
+	return nil
+}
+
+func migrate(ctx context.Context, cfg *gex.Config, to string) error {
+	typ, err := manager.ParseType(to)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	from := cfg.ManagerType
+	result, err := cfg.MigrateTo(ctx, typ)
+	if result != nil {
+		for _, t := range result.Migrated {
+			fmt.Fprintf(os.Stdout, "migrated %s\n", t)
+		}
+		for _, t := range result.Unmapped {
+			fmt.Fprintf(os.Stdout, "%s could not be mapped to a module version, the latest version is required instead\n", t)
+		}
+	}
+	if err != nil {
+		return errors.WithStack(err)
 	}
 
+	if from == manager.TypeDep {
+		fmt.Fprintln(os.Stdout, "Gopkg.toml and Gopkg.lock can be removed now")
+	}
+	return nil
+}
+
+func exportBundle(ctx context.Context, toolRepo tool.Repository, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = toolRepo.Export(ctx, f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+	}
 	return errors.WithStack(err)
 }
 
-func printHelp(w io.Writer) {
-	fmt.Fprintln(w, helpText)
-	pflag.PrintDefaults()
+func importBundle(ctx context.Context, toolRepo tool.Repository, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer f.Close()
+	return errors.WithStack(toolRepo.Import(ctx, f))
+}
+
+func exportScript(ctx context.Context, toolRepo tool.Repository, format string, args []string) error {
+	// `--export-script make` is parsed as `--export-script=sh make`
+	if format == string(tool.ScriptShell) && len(args) > 0 {
+		format = args[0]
+	}
+	f, err := tool.ParseScriptFormat(format)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(toolRepo.ExportScript(ctx, os.Stdout, f))
 }
 
-var (
-	helpText = `The implementation of clarify best practice for tool dependencies.
+func importFrom(ctx context.Context, toolRepo tool.Repository, cfg *gex.Config, format string, args []string) error {
+	imp, err := importer.Lookup(format)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	path := imp.DefaultPath()
+	if len(args) > 0 {
+		path = args[0]
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(cfg.WorkingDir, path)
+	}
 
-See https://github.com/golang/go/issues/25922#issuecomment-412992431
+	result, err := imp.Import(cfg.FS, path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read %s", path)
+	}
 
-Usage:
-  gex --init
-  gex --add [packages...]   Add new tool dependencies
-  go generate ./tools.go    Build tools
-  gex [command] [args]      Execute a tool
+	for _, s := range result.Unmapped {
+		fmt.Fprintf(os.Stdout, "%s could not be mapped to a package\n", s)
+	}
+	if len(result.Packages) == 0 {
+		return errors.Errorf("no tools were found in %s", path)
+	}
 
-Flags:`
-)
+	pkgs := make([]string, len(result.Packages))
+	for i, pkg := range result.Packages {
+		pkgs[i] = pkg.String()
+	}
+	err = toolRepo.Add(ctx, pkgs...)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for _, pkg := range pkgs {
+		fmt.Fprintf(os.Stdout, "imported %s\n", pkg)
+	}
+
+	return nil
+}
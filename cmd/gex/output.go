@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/izumin5210/gex/pkg/tool"
+)
+
+// Output formats
+const (
+	formatText = "text"
+	formatJSON = "json"
+)
+
+// jsonDocument is a document that is written into stdout with `--format json`.
+// Tools is always present (empty if the command does not report tools), and Error is present only if the command failed.
+type jsonDocument struct {
+	Tools []*jsonTool `json:"tools"`
+	Error *jsonError  `json:"error,omitempty"`
+}
+
+type jsonTool struct {
+	Name    string   `json:"name"`
+	Package string   `json:"package"`
+	Version string   `json:"version,omitempty"`
+	Groups  []string `json:"groups,omitempty"`
+	BinPath string   `json:"bin_path"`
+	Built   bool     `json:"built"`
+	// Cached, DurationMS and Error are reported for tools that are built by the command.
+	Cached     bool   `json:"cached,omitempty"`
+	DurationMS int64  `json:"duration_ms,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+type jsonError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func newJSONDocument() *jsonDocument {
+	return &jsonDocument{Tools: []*jsonTool{}}
+}
+
+func (d *jsonDocument) setError(code int, err error) {
+	if err == nil {
+		return
+	}
+	d.Error = &jsonError{Code: code, Message: err.Error()}
+}
+
+func (d *jsonDocument) write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return errors.WithStack(enc.Encode(d))
+}
+
+// buildRecorder is a tool.Observer that records results of builds to report them in JSON documents.
+type buildRecorder struct {
+	mu      sync.Mutex
+	results map[tool.Tool]*jsonTool
+}
+
+func newBuildRecorder() *buildRecorder {
+	return &buildRecorder{
+		results: make(map[tool.Tool]*jsonTool),
+	}
+}
+
+func (r *buildRecorder) OnEvent(ev tool.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch ev := ev.(type) {
+	case tool.BuildFinished:
+		res := &jsonTool{DurationMS: ev.Duration.Nanoseconds() / 1e6}
+		if ev.Err != nil {
+			res.Error = ev.Err.Error()
+		}
+		r.results[ev.Tool] = res
+	case tool.CacheHit:
+		r.results[ev.Tool] = &jsonTool{Cached: true}
+	}
+}
+
+// reportTools returns states of tools in the groups merged with the recorded build results.
+// If pkgs is not empty, only tools of the packages are reported.
+func reportTools(ctx context.Context, toolRepo tool.Repository, rec *buildRecorder, groups []string, pkgs []string) ([]*jsonTool, error) {
+	statuses, err := toolRepo.Status(ctx, groups...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	selected := make(map[tool.Tool]bool, len(pkgs))
+	for _, pkg := range pkgs {
+		selected[tool.Tool(strings.SplitN(pkg, "@", 2)[0])] = true
+	}
+
+	tools := make([]*jsonTool, 0, len(statuses))
+	for _, st := range statuses {
+		if len(selected) > 0 && !selected[st.Tool] {
+			continue
+		}
+		t := &jsonTool{
+			Name:    st.Tool.Name(),
+			Package: string(st.Tool),
+			Version: st.Version,
+			Groups:  st.Groups,
+			BinPath: st.BinPath,
+			Built:   st.Built,
+		}
+		if rec != nil {
+			rec.mu.Lock()
+			if res, ok := rec.results[st.Tool]; ok {
+				t.Cached, t.DurationMS, t.Error = res.Cached, res.DurationMS, res.Error
+			}
+			rec.mu.Unlock()
+		}
+		tools = append(tools, t)
+	}
+
+	return tools, nil
+}
+
+// report sets tools into the document, and returns err as it is.
+// Tools are reported on a best-effort basis if the command has failed.
+// It is no-op if the document is nil, i.e. the output format is text.
+func (d *jsonDocument) report(ctx context.Context, toolRepo tool.Repository, rec *buildRecorder, groups []string, pkgs []string, err error) error {
+	if d == nil {
+		return err
+	}
+	tools, rerr := reportTools(ctx, toolRepo, rec, groups, pkgs)
+	if rerr != nil {
+		if err == nil {
+			return errors.WithStack(rerr)
+		}
+		return err
+	}
+	d.Tools = tools
+	return err
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/izumin5210/gex/pkg/tool"
+)
+
+// Build states of tools shown by progressRenderer
+const (
+	progressQueued   = "queued"
+	progressBuilding = "building"
+	progressDone     = "done"
+	progressCached   = "cached"
+	progressFailed   = "failed"
+)
+
+// progressRenderer is a tool.Observer that shows progress of builds.
+// On terminals, it redraws a status line for each tool. Otherwise, it writes a log line when a build is started or finished.
+// Output of `go build` is buffered for each tool, and written into errW only if the build has failed.
+type progressRenderer struct {
+	mu   sync.Mutex
+	w    io.Writer
+	errW io.Writer
+	tty  bool
+	// trace is true if builds are run with `go build -x`, whose commands are not written with the output.
+	trace  bool
+	tools  []tool.Tool
+	states map[tool.Tool]*progressState
+	// lines is the number of status lines drawn last time.
+	lines int
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+type progressState struct {
+	status   string
+	start    time.Time
+	duration time.Duration
+	output   []string
+	// heredoc is true while contents of a here document printed by `go build -x` are received.
+	heredoc bool
+}
+
+func newProgressRenderer(w, errW io.Writer, tty bool) *progressRenderer {
+	return &progressRenderer{
+		w:      w,
+		errW:   errW,
+		tty:    tty,
+		states: make(map[tool.Tool]*progressState),
+	}
+}
+
+// isTerminal returns true if f is a terminal that interprets ANSI escape sequences.
+func isTerminal(f *os.File) bool {
+	if runtime.GOOS == "windows" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	st, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return st.Mode()&os.ModeCharDevice != 0
+}
+
+// Start shows the tools as queued, and starts redrawing elapsed times of builds on terminals.
+func (p *progressRenderer) Start(tools []tool.Tool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, t := range tools {
+		p.state(t)
+	}
+
+	if !p.tty {
+		return
+	}
+	p.redraw()
+	stop, done := make(chan struct{}), make(chan struct{})
+	p.stop, p.done = stop, done
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.mu.Lock()
+				p.redraw()
+				p.mu.Unlock()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop draws the final states, and writes output of failed builds on terminals.
+func (p *progressRenderer) Stop() {
+	p.mu.Lock()
+	stop, done := p.stop, p.done
+	p.stop = nil
+	p.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.tty {
+		return
+	}
+	p.redraw()
+	for _, t := range p.tools {
+		p.writeOutput(t)
+	}
+}
+
+// filterLog returns an observer that drops build events sent to o while status lines are redrawn on terminals,
+// since log lines written by o break the status lines.
+func (p *progressRenderer) filterLog(o tool.Observer) tool.Observer {
+	return tool.ObserverFunc(func(ev tool.Event) {
+		switch ev.(type) {
+		case tool.BuildStarted, tool.BuildFinished, tool.CacheHit:
+			p.mu.Lock()
+			active := p.stop != nil
+			p.mu.Unlock()
+			if active {
+				return
+			}
+		}
+		o.OnEvent(ev)
+	})
+}
+
+func (p *progressRenderer) OnEvent(ev tool.Event) {
+	now := time.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch ev := ev.(type) {
+	case tool.BuildStarted:
+		s := p.state(ev.Tool)
+		s.status, s.start = progressBuilding, now
+		p.log(ev.Tool)
+	case tool.BuildOutput:
+		s := p.state(ev.Tool)
+		if p.trace && s.isTrace(ev.Line) {
+			return
+		}
+		s.output = append(s.output, ev.Line)
+	case tool.BuildFinished:
+		s := p.state(ev.Tool)
+		s.status, s.duration = progressDone, ev.Duration
+		if ev.Err != nil {
+			s.status = progressFailed
+		}
+		p.log(ev.Tool)
+		if !p.tty {
+			p.writeOutput(ev.Tool)
+		}
+	case tool.CacheHit:
+		p.state(ev.Tool).status = progressCached
+		p.log(ev.Tool)
+	default:
+		return
+	}
+
+	if p.tty && p.stop != nil {
+		p.redraw()
+	}
+}
+
+// traceCommands are commands printed by `go build -x`.
+var traceCommands = map[string]bool{
+	"asm": true, "buildid": true, "cgo": true, "compile": true, "cover": true, "link": true, "pack": true, "vet": true,
+	"cat": true, "cd": true, "chmod": true, "cp": true, "echo": true, "ln": true, "mkdir": true, "mv": true, "rm": true, "touch": true,
+	"clang": true, "gcc": true,
+}
+
+// isTrace returns true if the line is printed by `go build -x`, such as commands, "WORK=..." and here documents.
+func (s *progressState) isTrace(line string) bool {
+	if s.heredoc {
+		s.heredoc = line != "EOF"
+		return true
+	}
+	if strings.Contains(line, "<< 'EOF'") {
+		// cat >$WORK/b001/importcfg << 'EOF' # internal
+		s.heredoc = true
+		return true
+	}
+	cmd := goToolCommand(line)
+	if cmd == "" {
+		// WORK=/tmp/go-build123456
+		return envAssignmentPattern.MatchString(line)
+	}
+	return traceCommands[cmd]
+}
+
+func (p *progressRenderer) state(t tool.Tool) *progressState {
+	s, ok := p.states[t]
+	if !ok {
+		s = &progressState{status: progressQueued}
+		p.states[t] = s
+		p.tools = append(p.tools, t)
+	}
+	return s
+}
+
+// log writes the state of the tool as a line if the output is not a terminal.
+func (p *progressRenderer) log(t tool.Tool) {
+	if p.tty {
+		return
+	}
+	fmt.Fprintln(p.w, p.line(t, 0))
+}
+
+func (p *progressRenderer) line(t tool.Tool, width int) string {
+	s := p.states[t]
+	line := fmt.Sprintf("%-*s  %-8s", width, t.Name(), s.status)
+	switch {
+	case s.status == progressBuilding && p.tty:
+		line += "  " + formatDuration(time.Since(s.start).Truncate(100*time.Millisecond))
+	case s.status == progressDone, s.status == progressFailed:
+		line += "  " + formatDuration(s.duration)
+	}
+	return strings.TrimRight(line, " ")
+}
+
+// redraw moves the cursor to the first status line and overwrites the lines.
+func (p *progressRenderer) redraw() {
+	var width int
+	for _, t := range p.tools {
+		if n := len(t.Name()); n > width {
+			width = n
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	if p.lines > 0 {
+		fmt.Fprintf(buf, "\x1b[%dA", p.lines)
+	}
+	for _, t := range p.tools {
+		buf.WriteString("\r\x1b[K")
+		buf.WriteString(p.line(t, width))
+		buf.WriteByte('\n')
+	}
+	p.lines = len(p.tools)
+	p.w.Write(buf.Bytes())
+}
+
+// writeOutput writes buffered output of `go build` if the build has failed.
+// The output starts with the "# <package>" line printed by `go build`.
+func (p *progressRenderer) writeOutput(t tool.Tool) {
+	s := p.states[t]
+	if s.status != progressFailed {
+		return
+	}
+	for _, l := range s.output {
+		fmt.Fprintln(p.errW, l)
+	}
+}
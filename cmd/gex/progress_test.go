@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/izumin5210/gex/pkg/tool"
+)
+
+func TestProgressRenderer_Trace(t *testing.T) {
+	var w, errW bytes.Buffer
+	p := newProgressRenderer(&w, &errW, false)
+	p.trace = true
+
+	mockgen := tool.Tool("github.com/golang/mock/mockgen")
+	p.Start([]tool.Tool{mockgen})
+	for _, line := range []string{
+		"WORK=/tmp/go-build123456",
+		"mkdir -p $WORK/b001/",
+		"cat >$WORK/b001/importcfg << 'EOF' # internal",
+		"# import config",
+		"packagefile fmt=/root/.cache/go-build/ab/abcdef-d",
+		"EOF",
+		"cd /home/src/awesomeapp",
+		"/usr/local/go/pkg/tool/linux_amd64/compile -o $WORK/b001/_pkg_.a -p main ./main.go",
+		"# github.com/golang/mock/mockgen",
+		"./main.go:3:1: syntax error: non-declaration statement outside function body",
+		"rm -r $WORK/b001/",
+	} {
+		p.OnEvent(tool.BuildOutput{Tool: mockgen, Line: line})
+	}
+	p.OnEvent(tool.BuildFinished{Tool: mockgen, Err: errors.New("exit status 2")})
+	p.Stop()
+
+	want := "# github.com/golang/mock/mockgen\n" +
+		"./main.go:3:1: syntax error: non-declaration statement outside function body\n"
+	if diff := cmp.Diff(want, errW.String()); diff != "" {
+		t.Errorf("unexpected output of the failed build: (-want +got)\n%s", diff)
+	}
+}
+
+func TestProgressRenderer(t *testing.T) {
+	mockgen := tool.Tool("github.com/golang/mock/mockgen")
+	golint := tool.Tool("golang.org/x/lint/golint")
+	stringer := tool.Tool("golang.org/x/tools/cmd/stringer")
+
+	run := func(p *progressRenderer) {
+		p.Start([]tool.Tool{mockgen, golint, stringer})
+		for _, ev := range []tool.Event{
+			tool.CacheHit{Tool: stringer},
+			tool.BuildStarted{Tool: mockgen},
+			tool.BuildOutput{Tool: mockgen, Line: "github.com/golang/mock/mockgen"},
+			tool.BuildStarted{Tool: golint},
+			tool.BuildOutput{Tool: golint, Line: "# golang.org/x/lint/golint"},
+			tool.BuildOutput{Tool: golint, Line: "../lint.go:3:1: syntax error"},
+			tool.BuildFinished{Tool: mockgen, Duration: 1200 * time.Millisecond},
+			tool.BuildFinished{Tool: golint, Duration: 300 * time.Millisecond, Err: errors.New("exit status 2")},
+		} {
+			p.OnEvent(ev)
+		}
+		p.Stop()
+	}
+	wantErr := "# golang.org/x/lint/golint\n../lint.go:3:1: syntax error\n"
+
+	t.Run("not terminal", func(t *testing.T) {
+		var w, errW bytes.Buffer
+		run(newProgressRenderer(&w, &errW, false))
+
+		want := "stringer  cached\n" +
+			"mockgen  building\n" +
+			"golint  building\n" +
+			"mockgen  done      1.2s\n" +
+			"golint  failed    300ms\n"
+		if diff := cmp.Diff(want, w.String()); diff != "" {
+			t.Errorf("unexpected progress: (-want +got)\n%s", diff)
+		}
+		if diff := cmp.Diff(wantErr, errW.String()); diff != "" {
+			t.Errorf("unexpected output of builds: (-want +got)\n%s", diff)
+		}
+	})
+
+	t.Run("terminal", func(t *testing.T) {
+		var w, errW bytes.Buffer
+		run(newProgressRenderer(&w, &errW, true))
+
+		first := "\r\x1b[Kmockgen   queued\n" +
+			"\r\x1b[Kgolint    queued\n" +
+			"\r\x1b[Kstringer  queued\n"
+		last := "\x1b[3A" +
+			"\r\x1b[Kmockgen   done      1.2s\n" +
+			"\r\x1b[Kgolint    failed    300ms\n" +
+			"\r\x1b[Kstringer  cached\n"
+		if got := w.String(); !strings.HasPrefix(got, first) || !strings.HasSuffix(got, last) {
+			t.Errorf("unexpected progress: %q", got)
+		}
+		if diff := cmp.Diff(wantErr, errW.String()); diff != "" {
+			t.Errorf("unexpected output of builds: (-want +got)\n%s", diff)
+		}
+	})
+}
+
+func TestProgressRenderer_filterLog(t *testing.T) {
+	mockgen := tool.Tool("github.com/golang/mock/mockgen")
+	events := []tool.Event{
+		tool.SyncStarted{},
+		tool.BuildStarted{Tool: mockgen},
+		tool.BuildFinished{Tool: mockgen},
+	}
+
+	for _, tty := range []bool{false, true} {
+		var got []tool.Event
+		p := newProgressRenderer(new(bytes.Buffer), new(bytes.Buffer), tty)
+		o := p.filterLog(tool.ObserverFunc(func(ev tool.Event) { got = append(got, ev) }))
+
+		p.Start([]tool.Tool{mockgen})
+		for _, ev := range events {
+			o.OnEvent(ev)
+		}
+		p.Stop()
+
+		want := events
+		if tty {
+			want = events[:1]
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("received events differ on the terminal=%t: (-want +got)\n%s", tty, diff)
+		}
+	}
+}
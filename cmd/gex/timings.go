@@ -0,0 +1,292 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/izumin5210/gex/pkg/tool"
+)
+
+// timingRecorder is a tool.Observer that records build times of tools.
+// Phases of builds are detected from commands printed by `go build -x`.
+type timingRecorder struct {
+	mu      sync.Mutex
+	timings map[tool.Tool]*toolTiming
+}
+
+type toolTiming struct {
+	Tool       tool.Tool
+	Start, End time.Time
+	Cached     bool
+	Err        error
+	// Packages is the number of packages compiled in the build. Packages in the build cache are not counted.
+	Packages int
+	// Compile and Link are times when the first compile command and the link command are started.
+	Compile, Link time.Time
+}
+
+func newTimingRecorder() *timingRecorder {
+	return &timingRecorder{
+		timings: make(map[tool.Tool]*toolTiming),
+	}
+}
+
+func (r *timingRecorder) OnEvent(ev tool.Event) {
+	now := time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch ev := ev.(type) {
+	case tool.BuildStarted:
+		r.timings[ev.Tool] = &toolTiming{Tool: ev.Tool, Start: now}
+	case tool.BuildOutput:
+		t, ok := r.timings[ev.Tool]
+		if !ok {
+			return
+		}
+		switch goToolCommand(ev.Line) {
+		case "compile":
+			t.Packages++
+			fallthrough
+		case "asm", "cgo":
+			if t.Compile.IsZero() {
+				t.Compile = now
+			}
+		case "link":
+			t.Link = now
+		}
+	case tool.BuildFinished:
+		t, ok := r.timings[ev.Tool]
+		if !ok {
+			t = &toolTiming{Tool: ev.Tool, Start: now.Add(-ev.Duration)}
+			r.timings[ev.Tool] = t
+		}
+		t.End, t.Err = now, ev.Err
+	case tool.CacheHit:
+		r.timings[ev.Tool] = &toolTiming{Tool: ev.Tool, Start: now, End: now, Cached: true}
+	}
+}
+
+var envAssignmentPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=`)
+
+// goToolCommand returns a name of the go tool executed in the line printed by `go build -x`, such as "compile" and "link".
+// e.g. GOROOT='/usr/local/go' /usr/local/go/pkg/tool/linux_amd64/link -o $WORK/b001/exe/a.out ...
+func goToolCommand(line string) string {
+	for _, f := range shellFields(line) {
+		if envAssignmentPattern.MatchString(f) {
+			continue
+		}
+		return strings.TrimSuffix(filepath.Base(strings.Trim(f, `"'`)), ".exe")
+	}
+	return ""
+}
+
+// shellFields splits the line around spaces that are not quoted.
+// e.g. CGO_LDFLAGS='"-g" "-O2"' /usr/local/go/pkg/tool/linux_amd64/cgo -> [CGO_LDFLAGS='"-g" "-O2"', /usr/local/go/pkg/tool/linux_amd64/cgo]
+func shellFields(line string) []string {
+	var (
+		fields []string
+		quote  rune
+		start  = -1
+	)
+	for i, c := range line {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+			continue
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ' ' || c == '\t':
+			if start >= 0 {
+				fields = append(fields, line[start:i])
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		fields = append(fields, line[start:])
+	}
+	return fields
+}
+
+func (t *toolTiming) Duration() time.Duration {
+	return t.End.Sub(t.Start)
+}
+
+// phase is a span of the build.
+type phase struct {
+	Name       string
+	Start, End time.Time
+}
+
+// Phases returns spans of the build: "setup" (loading packages), "compile" and "link".
+func (t *toolTiming) Phases() []phase {
+	if t.Cached {
+		return nil
+	}
+	var phases []phase
+	setupEnd := t.End
+	switch {
+	case !t.Compile.IsZero():
+		setupEnd = t.Compile
+	case !t.Link.IsZero():
+		setupEnd = t.Link
+	}
+	phases = append(phases, phase{Name: "setup", Start: t.Start, End: setupEnd})
+	if !t.Compile.IsZero() {
+		compileEnd := t.End
+		if !t.Link.IsZero() {
+			compileEnd = t.Link
+		}
+		phases = append(phases, phase{Name: "compile", Start: t.Compile, End: compileEnd})
+	}
+	if !t.Link.IsZero() {
+		phases = append(phases, phase{Name: "link", Start: t.Link, End: t.End})
+	}
+	return phases
+}
+
+// sorted returns timings of rebuilt tools in descending order of durations, and then cached tools.
+func (r *timingRecorder) sorted() []*toolTiming {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	timings := make([]*toolTiming, 0, len(r.timings))
+	for _, t := range r.timings {
+		timings = append(timings, t)
+	}
+	sort.Slice(timings, func(i, j int) bool {
+		ti, tj := timings[i], timings[j]
+		if ti.Cached != tj.Cached {
+			return !ti.Cached
+		}
+		if di, dj := ti.Duration(), tj.Duration(); di != dj {
+			return di > dj
+		}
+		return ti.Tool < tj.Tool
+	})
+	return timings
+}
+
+// printSummary writes a table of build times.
+func (r *timingRecorder) printSummary(w io.Writer) {
+	timings := r.sorted()
+	if len(timings) == 0 {
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TOOL\tTIME\tBUILD\tPACKAGES\tSETUP\tCOMPILE\tLINK")
+
+	var (
+		start, end time.Time
+		sum        time.Duration
+		rebuilt    int
+		failed     int
+	)
+	for _, t := range timings {
+		if t.Cached {
+			fmt.Fprintf(tw, "%s\t-\tcached\t\t\t\t\n", t.Tool.Name())
+			continue
+		}
+		status := "rebuilt"
+		if t.Err != nil {
+			status = "failed"
+			failed++
+		}
+		cols := map[string]string{}
+		for _, p := range t.Phases() {
+			cols[p.Name] = formatDuration(p.End.Sub(p.Start))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n", t.Tool.Name(), formatDuration(t.Duration()), status, t.Packages, cols["setup"], cols["compile"], cols["link"])
+
+		if start.IsZero() || t.Start.Before(start) {
+			start = t.Start
+		}
+		if t.End.After(end) {
+			end = t.End
+		}
+		sum += t.Duration()
+		rebuilt++
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "\n%d rebuilt, %d failed and %d cached tool(s) in %s (%s in total of each tool)\n", rebuilt-failed, failed, len(timings)-rebuilt, formatDuration(end.Sub(start)), formatDuration(sum))
+	if rebuilt > 1 && sum > 0 {
+		slowest := timings[0]
+		fmt.Fprintf(w, "the slowest tool is %s, that takes %d%% of the build time\n", slowest.Tool.Name(), int(100*slowest.Duration()/sum))
+	}
+}
+
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
+
+// traceEvent is an event of the Trace Event Format, that can be opened with chrome://tracing or Perfetto.
+// See https://docs.google.com/document/d/1CvAClvFfyA5R-PhYUmn5OOQtYMH4h6I0nSsKchNAySU
+type traceEvent struct {
+	Name  string                 `json:"name"`
+	Cat   string                 `json:"cat,omitempty"`
+	Ph    string                 `json:"ph"`
+	Ts    int64                  `json:"ts"`
+	Dur   int64                  `json:"dur,omitempty"`
+	Scope string                 `json:"s,omitempty"`
+	Pid   int                    `json:"pid"`
+	Tid   int                    `json:"tid"`
+	Args  map[string]interface{} `json:"args,omitempty"`
+}
+
+// writeTrace writes builds of tools in the Trace Event Format. Each tool is shown as a thread.
+func (r *timingRecorder) writeTrace(w io.Writer) error {
+	timings := r.sorted()
+
+	var origin time.Time
+	for _, t := range timings {
+		if origin.IsZero() || t.Start.Before(origin) {
+			origin = t.Start
+		}
+	}
+	micros := func(t time.Time) int64 { return t.Sub(origin).Nanoseconds() / 1e3 }
+
+	events := make([]traceEvent, 0, 4*len(timings))
+	for i, t := range timings {
+		tid := i + 1
+		events = append(events, traceEvent{Name: "thread_name", Ph: "M", Pid: 1, Tid: tid, Args: map[string]interface{}{"name": t.Tool.Name()}})
+		args := map[string]interface{}{"package": string(t.Tool), "cached": t.Cached}
+		if t.Cached {
+			events = append(events, traceEvent{Name: t.Tool.Name(), Cat: "cache", Ph: "i", Scope: "t", Ts: micros(t.Start), Pid: 1, Tid: tid, Args: args})
+			continue
+		}
+		args["packages"] = t.Packages
+		if t.Err != nil {
+			args["error"] = t.Err.Error()
+		}
+		events = append(events, traceEvent{Name: t.Tool.Name(), Cat: "build", Ph: "X", Ts: micros(t.Start), Dur: micros(t.End) - micros(t.Start), Pid: 1, Tid: tid, Args: args})
+		for _, p := range t.Phases() {
+			events = append(events, traceEvent{Name: p.Name, Cat: "phase", Ph: "X", Ts: micros(p.Start), Dur: micros(p.End) - micros(p.Start), Pid: 1, Tid: tid})
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	return errors.WithStack(enc.Encode(map[string]interface{}{
+		"traceEvents":     events,
+		"displayTimeUnit": "ms",
+	}))
+}
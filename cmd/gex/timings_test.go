@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/izumin5210/gex/pkg/tool"
+)
+
+func TestGoToolCommand(t *testing.T) {
+	cases := []struct {
+		line string
+		want string
+	}{
+		{line: "/usr/local/go/pkg/tool/linux_amd64/compile -o $WORK/b001/_pkg_.a -trimpath \"$WORK/b001=>\" -p main ./main.go", want: "compile"},
+		{line: "GOROOT='/usr/local/go' /usr/local/go/pkg/tool/linux_amd64/link -o $WORK/b001/exe/a.out -importcfg $WORK/b001/importcfg.link", want: "link"},
+		{line: "TERM='dumb' CGO_LDFLAGS='\"-g\" \"-O2\"' /usr/local/go/pkg/tool/linux_amd64/cgo -objdir $WORK/b002/", want: "cgo"},
+		{line: "'/usr/local/go/pkg/tool/linux_amd64/asm' -p runtime -o $WORK/b003/asm.o", want: "asm"},
+		{line: "\"/go/pkg/tool/windows_amd64/link.exe\" -o $WORK/b001/exe/a.out.exe", want: "link"},
+		{line: "mkdir -p $WORK/b001/", want: "mkdir"},
+		{line: "WORK=/tmp/go-build123456", want: ""},
+		{line: "", want: ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.line, func(t *testing.T) {
+			if got := goToolCommand(tc.line); got != tc.want {
+				t.Errorf("goToolCommand() returned %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestToolTiming_Phases(t *testing.T) {
+	start := time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+	at := func(sec int) time.Time { return start.Add(time.Duration(sec) * time.Second) }
+
+	cases := []struct {
+		test   string
+		timing toolTiming
+		want   []phase
+	}{
+		{
+			test:   "cached",
+			timing: toolTiming{Start: start, End: start, Cached: true},
+		},
+		{
+			test:   "setup only",
+			timing: toolTiming{Start: start, End: at(1)},
+			want:   []phase{{Name: "setup", Start: start, End: at(1)}},
+		},
+		{
+			test:   "compile and link",
+			timing: toolTiming{Start: start, Compile: at(1), Link: at(5), End: at(7)},
+			want: []phase{
+				{Name: "setup", Start: start, End: at(1)},
+				{Name: "compile", Start: at(1), End: at(5)},
+				{Name: "link", Start: at(5), End: at(7)},
+			},
+		},
+		{
+			test:   "compile only",
+			timing: toolTiming{Start: start, Compile: at(1), End: at(3)},
+			want: []phase{
+				{Name: "setup", Start: start, End: at(1)},
+				{Name: "compile", Start: at(1), End: at(3)},
+			},
+		},
+		{
+			test:   "link only",
+			timing: toolTiming{Start: start, Link: at(2), End: at(3)},
+			want: []phase{
+				{Name: "setup", Start: start, End: at(2)},
+				{Name: "link", Start: at(2), End: at(3)},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, tc.timing.Phases()); diff != "" {
+				t.Errorf("Phases() returned unexpected phases: (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func newTestTimingRecorder() *timingRecorder {
+	start := time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+
+	r := newTimingRecorder()
+	for _, t := range []*toolTiming{
+		{Tool: "golang.org/x/tools/cmd/stringer", Start: at(0), End: at(0), Cached: true},
+		{Tool: "github.com/golang/mock/mockgen", Start: at(0), Compile: at(100), Link: at(300), End: at(400), Packages: 3},
+		{Tool: "golang.org/x/lint/golint", Start: at(50), End: at(100), Err: errors.New("exit status 2")},
+		{Tool: "github.com/golangci/golangci-lint/cmd/golangci-lint", Start: at(0), Compile: at(200), Link: at(900), End: at(1000), Packages: 10},
+	} {
+		r.timings[t.Tool] = t
+	}
+	return r
+}
+
+func TestTimingRecorder_sorted(t *testing.T) {
+	var got []tool.Tool
+	for _, t := range newTestTimingRecorder().sorted() {
+		got = append(got, t.Tool)
+	}
+
+	want := []tool.Tool{
+		"github.com/golangci/golangci-lint/cmd/golangci-lint",
+		"github.com/golang/mock/mockgen",
+		"golang.org/x/lint/golint",
+		"golang.org/x/tools/cmd/stringer",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("sorted() returned unexpected order: (-want +got)\n%s", diff)
+	}
+}
+
+func TestTimingRecorder_printSummary(t *testing.T) {
+	buf := new(bytes.Buffer)
+	newTestTimingRecorder().printSummary(buf)
+
+	want := `TOOL           TIME   BUILD    PACKAGES  SETUP  COMPILE  LINK
+golangci-lint  1s     rebuilt  10        200ms  700ms    100ms
+mockgen        400ms  rebuilt  3         100ms  200ms    100ms
+golint         50ms   failed   0         50ms
+stringer       -      cached
+
+2 rebuilt, 1 failed and 1 cached tool(s) in 1s (1.45s in total of each tool)
+the slowest tool is golangci-lint, that takes 68% of the build time
+`
+	// tabwriter pads empty cells at the end of rows
+	var lines []string
+	for _, l := range strings.Split(buf.String(), "\n") {
+		lines = append(lines, strings.TrimRight(l, " "))
+	}
+	if diff := cmp.Diff(want, strings.Join(lines, "\n")); diff != "" {
+		t.Errorf("printSummary() wrote unexpected summary: (-want +got)\n%s", diff)
+	}
+}
+
+func TestTimingRecorder_writeTrace(t *testing.T) {
+	buf := new(bytes.Buffer)
+	err := newTestTimingRecorder().writeTrace(buf)
+	if err != nil {
+		t.Fatalf("writeTrace() returned an error: %v", err)
+	}
+
+	var got struct {
+		TraceEvents     []traceEvent `json:"traceEvents"`
+		DisplayTimeUnit string       `json:"displayTimeUnit"`
+	}
+	err = json.Unmarshal(buf.Bytes(), &got)
+	if err != nil {
+		t.Fatalf("failed to decode the trace: %v", err)
+	}
+
+	if got, want := got.DisplayTimeUnit, "ms"; got != want {
+		t.Errorf("displayTimeUnit is %q, want %q", got, want)
+	}
+
+	name := func(name string) map[string]interface{} { return map[string]interface{}{"name": name} }
+	want := []traceEvent{
+		{Name: "thread_name", Ph: "M", Pid: 1, Tid: 1, Args: name("golangci-lint")},
+		{Name: "golangci-lint", Cat: "build", Ph: "X", Ts: 0, Dur: 1000000, Pid: 1, Tid: 1, Args: map[string]interface{}{"package": "github.com/golangci/golangci-lint/cmd/golangci-lint", "cached": false, "packages": float64(10)}},
+		{Name: "setup", Cat: "phase", Ph: "X", Ts: 0, Dur: 200000, Pid: 1, Tid: 1},
+		{Name: "compile", Cat: "phase", Ph: "X", Ts: 200000, Dur: 700000, Pid: 1, Tid: 1},
+		{Name: "link", Cat: "phase", Ph: "X", Ts: 900000, Dur: 100000, Pid: 1, Tid: 1},
+		{Name: "thread_name", Ph: "M", Pid: 1, Tid: 2, Args: name("mockgen")},
+		{Name: "mockgen", Cat: "build", Ph: "X", Ts: 0, Dur: 400000, Pid: 1, Tid: 2, Args: map[string]interface{}{"package": "github.com/golang/mock/mockgen", "cached": false, "packages": float64(3)}},
+		{Name: "setup", Cat: "phase", Ph: "X", Ts: 0, Dur: 100000, Pid: 1, Tid: 2},
+		{Name: "compile", Cat: "phase", Ph: "X", Ts: 100000, Dur: 200000, Pid: 1, Tid: 2},
+		{Name: "link", Cat: "phase", Ph: "X", Ts: 300000, Dur: 100000, Pid: 1, Tid: 2},
+		{Name: "thread_name", Ph: "M", Pid: 1, Tid: 3, Args: name("golint")},
+		{Name: "golint", Cat: "build", Ph: "X", Ts: 50000, Dur: 50000, Pid: 1, Tid: 3, Args: map[string]interface{}{"package": "golang.org/x/lint/golint", "cached": false, "packages": float64(0), "error": "exit status 2"}},
+		{Name: "setup", Cat: "phase", Ph: "X", Ts: 50000, Dur: 50000, Pid: 1, Tid: 3},
+		{Name: "thread_name", Ph: "M", Pid: 1, Tid: 4, Args: name("stringer")},
+		{Name: "stringer", Cat: "cache", Ph: "i", Scope: "t", Ts: 0, Pid: 1, Tid: 4, Args: map[string]interface{}{"package": "golang.org/x/tools/cmd/stringer", "cached": true}},
+	}
+	if diff := cmp.Diff(want, got.TraceEvents); diff != "" {
+		t.Errorf("writeTrace() wrote unexpected events: (-want +got)\n%s", diff)
+	}
+}
+
+func TestTimingRecorder_OnEvent(t *testing.T) {
+	r := newTimingRecorder()
+	mockgen := tool.Tool("github.com/golang/mock/mockgen")
+	for _, ev := range []tool.Event{
+		tool.BuildStarted{Tool: mockgen},
+		tool.BuildOutput{Tool: mockgen, Line: "WORK=/tmp/go-build123456"},
+		tool.BuildOutput{Tool: mockgen, Line: "/usr/local/go/pkg/tool/linux_amd64/compile -o $WORK/b002/_pkg_.a -p fmt"},
+		tool.BuildOutput{Tool: mockgen, Line: "GOROOT='/usr/local/go' /usr/local/go/pkg/tool/linux_amd64/compile -o $WORK/b001/_pkg_.a -p main"},
+		tool.BuildOutput{Tool: mockgen, Line: "/usr/local/go/pkg/tool/linux_amd64/link -o $WORK/b001/exe/a.out"},
+		tool.BuildFinished{Tool: mockgen},
+		tool.CacheHit{Tool: "golang.org/x/tools/cmd/stringer"},
+	} {
+		r.OnEvent(ev)
+	}
+
+	timings := r.sorted()
+	if got, want := len(timings), 2; got != want {
+		t.Fatalf("%d tools are recorded, want %d", got, want)
+	}
+	if got := timings[0]; got.Tool != mockgen || got.Packages != 2 || got.Compile.IsZero() || got.Link.IsZero() || got.Cached {
+		t.Errorf("unexpected timing of mockgen: %+v", got)
+	}
+	if got := timings[1]; !got.Cached {
+		t.Errorf("unexpected timing of stringer: %+v", got)
+	}
+}
@@ -5,14 +5,19 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/izumin5210/execx"
 	"github.com/pkg/errors"
 	"github.com/spf13/afero"
 
 	"github.com/izumin5210/gex/pkg/manager"
-	"github.com/izumin5210/gex/pkg/manager/dep"
-	"github.com/izumin5210/gex/pkg/manager/mod"
+	// built-in dependencies management tools
+	_ "github.com/izumin5210/gex/pkg/manager/dep"
+	_ "github.com/izumin5210/gex/pkg/manager/gopath"
+	_ "github.com/izumin5210/gex/pkg/manager/mod"
 	"github.com/izumin5210/gex/pkg/tool"
 )
 
@@ -31,6 +36,31 @@ type Config struct {
 	BinDirName   string
 	ManagerType  manager.Type
 
+	// Env contains additional environment variables as "KEY=value" for managers and tools.
+	Env []string
+	// ToolEnv contains additional environment variables for each tool, keyed by the tool name.
+	ToolEnv map[string][]string
+
+	// Offline prevents the go command from accessing the network when building tools.
+	// Sources should be downloaded in advance (e.g. with Repository.Download).
+	Offline bool
+
+	// LockTimeout is a duration to wait for other gex processes that build tools in the same project.
+	LockTimeout time.Duration
+
+	// Observer receives progress events of operations on tools. Events are written into Logger if nil.
+	Observer tool.Observer
+
+	// BuildFlags contains additional flags for `go build` to build tools (e.g. "-x").
+	BuildFlags []string
+	// CaptureBuildOutput makes stderr of `go build` sent to Observer as tool.BuildOutput events
+	// instead of written into ErrWriter.
+	CaptureBuildOutput bool
+
+	// GexPath is an absolute path of the gex executable that shims written by Repository.WriteShims execute.
+	// Shims execute gex with `go run` in the module if empty.
+	GexPath string
+
 	Verbose bool
 	Logger  *log.Logger
 }
@@ -51,6 +81,7 @@ func createDefaultConfig() *Config {
 		Exec:         execx.New(),
 		WorkingDir:   wd,
 		ManifestName: "tools.go",
+		LockTimeout:  10 * time.Minute,
 		BinDirName:   "bin",
 		Logger:       log.New(ioutil.Discard, "", 0),
 	}
@@ -67,15 +98,36 @@ func (c *Config) Create() (tool.Repository, error) {
 		return nil, errors.WithStack(err)
 	}
 
-	return tool.NewRepository(executor, manager, c.ManagerType, &tool.Config{
+	return tool.NewRepository(executor, manager, c.ManagerType, c.toolConfig()), nil
+}
+
+// BinDir returns a path of the directory that contains built tools.
+func (c *Config) BinDir() string {
+	c.setDefaultsIfNeeded()
+	return c.toolConfig().BinDir()
+}
+
+func (c *Config) toolConfig() *tool.Config {
+	cfg := &tool.Config{
 		FS:           c.FS,
 		WorkingDir:   c.WorkingDir,
 		RootDir:      c.RootDir,
 		ManifestName: c.ManifestName,
 		BinDirName:   c.BinDirName,
+		ToolEnv:      c.ToolEnv,
 		Verbose:      c.Verbose,
 		Log:          c.Logger,
-	}), nil
+		Observer:     c.Observer,
+
+		BuildFlags:         c.BuildFlags,
+		CaptureBuildOutput: c.CaptureBuildOutput,
+		GexPath:            c.GexPath,
+	}
+	// flock(2) works only with files on the OS filesystem
+	if _, ok := c.FS.(*afero.OsFs); ok {
+		cfg.Locker = tool.NewFileLocker(filepath.Join(cfg.BinDir(), ".gex.lock"), c.LockTimeout)
+	}
+	return cfg
 }
 
 func (c *Config) setDefaultsIfNeeded() {
@@ -108,10 +160,17 @@ func (c *Config) setDefaultsIfNeeded() {
 	if c.Logger == nil {
 		c.Logger = d.Logger
 	}
+	if c.LockTimeout == 0 {
+		c.LockTimeout = d.LockTimeout
+	}
 
 	if c.ManagerType == manager.TypeUnknown {
 		c.ManagerType, c.RootDir = manager.DetectType(c.WorkingDir, c.FS, c.Exec)
 	}
+	if c.ManagerType == manager.TypeUnknown {
+		// neither Gopkg.toml nor go.mod exist
+		c.ManagerType = manager.TypeGOPATH
+	}
 
 	if rootDir, err := manager.FindRoot(c.WorkingDir, c.FS, c.ManifestName); err == nil {
 		if len(rootDir) > len(c.RootDir) {
@@ -125,19 +184,47 @@ func (c *Config) createManager() (
 	manager.Executor,
 	error,
 ) {
-	executor := manager.NewExecutor(c.Exec, c.OutWriter, c.ErrWriter, c.InReader, c.WorkingDir, c.Logger)
-	var (
-		m manager.Interface
-	)
-
-	switch c.ManagerType {
-	case manager.TypeModules:
-		m = mod.NewManager(executor)
-	case manager.TypeDep:
-		m = dep.NewManager(executor, c.RootDir, c.WorkingDir)
-	default:
-		return nil, nil, errors.New("failed to detect a dependencies management tool")
+	env := c.Environ()
+	executor := manager.NewExecutorWithEnv(c.Exec, c.OutWriter, c.ErrWriter, c.InReader, c.WorkingDir, env, c.Logger)
+	m, err := manager.New(c.ManagerType, &manager.Options{
+		Executor:   executor,
+		FS:         c.FS,
+		RootDir:    c.RootDir,
+		WorkingDir: c.WorkingDir,
+		ErrWriter:  c.ErrWriter,
+	})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to detect a dependencies management tool")
 	}
 
 	return m, executor, nil
 }
+
+// Environ returns environment variables for managers and tools.
+// The bin directory is prepended to PATH, and variables for Offline and Env are added.
+func (c *Config) Environ() []string {
+	c.setDefaultsIfNeeded()
+	return manager.Environ(os.Environ(), c.toolConfig().BinDir(), c.environ()...)
+}
+
+func (c *Config) environ() []string {
+	var env []string
+	if c.Offline {
+		env = append(env, "GOPROXY=off")
+		if c.ManagerType == manager.TypeModules {
+			env = append(env, "GOFLAGS="+withGoFlag(os.Getenv("GOFLAGS"), "-mod=mod"))
+		}
+	}
+	return append(env, c.Env...)
+}
+
+// withGoFlag appends the flag to GOFLAGS unless a flag of the same name is set by the user.
+func withGoFlag(goflags, flag string) string {
+	name := strings.SplitN(flag, "=", 2)[0]
+	for _, f := range strings.Fields(goflags) {
+		if f == name || strings.HasPrefix(f, name+"=") {
+			return goflags
+		}
+	}
+	return strings.TrimSpace(goflags + " " + flag)
+}
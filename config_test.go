@@ -0,0 +1,285 @@
+package gex_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/izumin5210/execx"
+	"github.com/spf13/afero"
+
+	"github.com/izumin5210/gex"
+	"github.com/izumin5210/gex/pkg/manager"
+)
+
+func TestConfig_BinDir(t *testing.T) {
+	rootDir := filepath.FromSlash("/go/src/awesomeapp")
+
+	if v, ok := os.LookupEnv("GO111MODULE"); ok {
+		defer func() { os.Setenv("GO111MODULE", v) }()
+		os.Unsetenv("GO111MODULE")
+	}
+
+	createExec := func(t *testing.T, gomod string) *execx.Executor {
+		t.Helper()
+		return execx.New(
+			execx.WithFakeProcess(func(_ context.Context, cmd *exec.Cmd) error {
+				fmt.Fprintln(cmd.Stdout, gomod)
+				return nil
+			}),
+		)
+	}
+	createFS := func(t *testing.T, files ...string) afero.Fs {
+		t.Helper()
+		fs := afero.NewMemMapFs()
+		for _, f := range files {
+			err := afero.WriteFile(fs, filepath.Join(rootDir, f), []byte(""), 0644)
+			if err != nil {
+				t.Fatalf("failed to write %s: %v", f, err)
+			}
+		}
+		return fs
+	}
+
+	cases := []struct {
+		test       string
+		workingDir string
+		fs         afero.Fs
+		exec       *execx.Executor
+		want       string
+	}{
+		{
+			test:       "modules",
+			workingDir: rootDir,
+			fs:         createFS(t, "go.mod", "tools.go"),
+			exec:       createExec(t, filepath.Join(rootDir, "go.mod")),
+			want:       filepath.Join(rootDir, "bin"),
+		},
+		{
+			test:       "modules from subdirectory",
+			workingDir: filepath.Join(rootDir, "foo", "bar"),
+			fs:         createFS(t, "go.mod", "tools.go", filepath.Join("foo", "bar", "bar.go")),
+			exec:       createExec(t, filepath.Join(rootDir, "go.mod")),
+			want:       filepath.Join(rootDir, "bin"),
+		},
+		{
+			test:       "dep from subdirectory",
+			workingDir: filepath.Join(rootDir, "foo"),
+			fs:         createFS(t, "Gopkg.toml", "tools.go", filepath.Join("foo", "foo.go")),
+			exec:       createExec(t, ""),
+			want:       filepath.Join(rootDir, "bin"),
+		},
+		{
+			test:       "manifest in subdirectory of the module",
+			workingDir: filepath.Join(rootDir, "tools", "foo"),
+			fs:         createFS(t, "go.mod", filepath.Join("tools", "tools.go"), filepath.Join("tools", "foo", "foo.go")),
+			exec:       createExec(t, filepath.Join(rootDir, "go.mod")),
+			want:       filepath.Join(rootDir, "tools", "bin"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			cfg := &gex.Config{
+				FS:         tc.fs,
+				Exec:       tc.exec,
+				WorkingDir: tc.workingDir,
+			}
+
+			if got, want := cfg.BinDir(), tc.want; got != want {
+				t.Errorf("BinDir() returned %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+// fakeGo records go commands and their environment variables.
+// Binaries are written into fs for `go build -o`.
+// Same as Go 1.14 and later, commands fail if the module has a vendor directory without modules.txt.
+type fakeGo struct {
+	fs     afero.Fs
+	gomod  string
+	stdout map[string]string
+	// fail is a subcommand that fails (e.g. "build").
+	fail string
+	cmds [][]string
+	env  [][]string
+}
+
+func (g *fakeGo) exec() *execx.Executor {
+	return execx.New(execx.WithFakeProcess(func(_ context.Context, cmd *exec.Cmd) error {
+		args := cmd.Args[1:]
+		if len(args) == 2 && args[0] == "env" && args[1] == "GOMOD" {
+			fmt.Fprintln(cmd.Stdout, g.gomod)
+			return nil
+		}
+		g.cmds = append(g.cmds, args)
+		g.env = append(g.env, cmd.Env)
+		if args[0] == g.fail {
+			return errors.New("exit status 1")
+		}
+		if g.gomod != "" {
+			vendorDir := filepath.Join(filepath.Dir(g.gomod), "vendor")
+			if ok, _ := afero.Exists(g.fs, vendorDir); ok {
+				if ok, _ := afero.Exists(g.fs, filepath.Join(vendorDir, "modules.txt")); !ok {
+					return errors.New("go: inconsistent vendoring")
+				}
+			}
+		}
+		if args[0] == "build" {
+			for i, a := range args {
+				if a == "-o" {
+					return afero.WriteFile(g.fs, args[i+1], []byte("bin"), 0755)
+				}
+			}
+		}
+		fmt.Fprint(cmd.Stdout, g.stdout[args[0]])
+		return nil
+	}))
+}
+
+func createModule(t *testing.T, rootDir string) afero.Fs {
+	t.Helper()
+	fs := afero.NewMemMapFs()
+	files := map[string]string{
+		"go.mod": "module awesomeapp\n\nrequire github.com/golang/mock v1.3.1\n",
+		"tools.go": `// +build tools
+
+package tools
+
+import _ "github.com/golang/mock/mockgen"
+`,
+	}
+	for name, body := range files {
+		err := afero.WriteFile(fs, filepath.Join(rootDir, name), []byte(body), 0644)
+		if err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	return fs
+}
+
+func TestConfig_Download(t *testing.T) {
+	rootDir := filepath.FromSlash("/go/src/awesomeapp")
+	fs := createModule(t, rootDir)
+	g := &fakeGo{
+		fs:     fs,
+		gomod:  filepath.Join(rootDir, "go.mod"),
+		stdout: map[string]string{"list": "github.com/golang/mock\ngithub.com/golang/mock\n"},
+	}
+	cfg := &gex.Config{
+		FS:          fs,
+		Exec:        g.exec(),
+		WorkingDir:  rootDir,
+		ManagerType: manager.TypeModules,
+		RootDir:     rootDir,
+	}
+
+	toolRepo, err := cfg.Create()
+	if err != nil {
+		t.Fatalf("Create() returned an error: %v", err)
+	}
+	err = toolRepo.Download(context.Background())
+	if err != nil {
+		t.Fatalf("Download() returned an error: %v", err)
+	}
+
+	want := [][]string{
+		{"list", "-deps", "-f", "{{with .Module}}{{if not .Main}}{{.Path}}{{end}}{{end}}", "github.com/golang/mock/mockgen"},
+		{"mod", "download", "github.com/golang/mock"},
+	}
+	if diff := cmp.Diff(want, g.cmds); diff != "" {
+		t.Errorf("executed commands differ: (-want +got)\n%s", diff)
+	}
+}
+
+func TestConfig_Offline(t *testing.T) {
+	rootDir := filepath.FromSlash("/go/src/awesomeapp")
+
+	if v, ok := os.LookupEnv("GOFLAGS"); ok {
+		defer os.Setenv("GOFLAGS", v)
+	} else {
+		defer os.Unsetenv("GOFLAGS")
+	}
+
+	cases := []struct {
+		test        string
+		offline     bool
+		goflags     string
+		wantProxy   string
+		wantGoFlags string
+	}{
+		{
+			test:        "online",
+			goflags:     "-trimpath",
+			wantGoFlags: "-trimpath",
+		},
+		{
+			test:        "offline",
+			offline:     true,
+			wantProxy:   "off",
+			wantGoFlags: "-mod=mod",
+		},
+		{
+			test:        "offline with GOFLAGS",
+			offline:     true,
+			goflags:     "-trimpath",
+			wantProxy:   "off",
+			wantGoFlags: "-trimpath -mod=mod",
+		},
+		{
+			test:        "offline with -mod in GOFLAGS",
+			offline:     true,
+			goflags:     "-mod=readonly",
+			wantProxy:   "off",
+			wantGoFlags: "-mod=readonly",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			os.Setenv("GOFLAGS", tc.goflags)
+			fs := createModule(t, rootDir)
+			g := &fakeGo{fs: fs, gomod: filepath.Join(rootDir, "go.mod")}
+			cfg := &gex.Config{
+				FS:          fs,
+				Exec:        g.exec(),
+				WorkingDir:  rootDir,
+				ManagerType: manager.TypeModules,
+				RootDir:     rootDir,
+				Offline:     tc.offline,
+			}
+
+			toolRepo, err := cfg.Create()
+			if err != nil {
+				t.Fatalf("Create() returned an error: %v", err)
+			}
+			_, err = toolRepo.Build(context.Background(), "github.com/golang/mock/mockgen")
+			if err != nil {
+				t.Fatalf("Build() returned an error: %v", err)
+			}
+
+			if got, want := len(g.env), 1; got != want {
+				t.Fatalf("%d commands are executed, want %d", got, want)
+			}
+			env := make(map[string]string)
+			for _, kv := range g.env[0] {
+				if kv := strings.SplitN(kv, "=", 2); len(kv) == 2 {
+					env[kv[0]] = kv[1]
+				}
+			}
+			if got, want := env["GOPROXY"], tc.wantProxy; tc.offline && got != want {
+				t.Errorf("GOPROXY is %q, want %q", got, want)
+			}
+			if got, want := env["GOFLAGS"], tc.wantGoFlags; got != want {
+				t.Errorf("GOFLAGS is %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -3,10 +3,12 @@ module github.com/izumin5210/gex
 go 1.11
 
 require (
+	github.com/BurntSushi/toml v0.3.1
 	github.com/bradleyjkemp/cupaloy/v2 v2.5.0
 	github.com/google/go-cmp v0.4.0
 	github.com/izumin5210/execx v0.1.0
 	github.com/pkg/errors v0.9.1
 	github.com/spf13/afero v1.2.2
 	github.com/spf13/pflag v1.0.5
+	golang.org/x/sys v0.0.0-20191018095205-727590c5006e
 )
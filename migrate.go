@@ -0,0 +1,159 @@
+package gex
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+
+	"github.com/izumin5210/gex/pkg/manager"
+	"github.com/izumin5210/gex/pkg/manager/dep"
+	"github.com/izumin5210/gex/pkg/tool"
+)
+
+// MigrationResult contains tools moved to another dependencies management tool.
+type MigrationResult struct {
+	// Migrated contains tools pinned at the same revisions as before.
+	Migrated []tool.Tool
+	// Unmapped contains tools whose revisions could not be mapped to module versions.
+	// Their latest versions are required instead.
+	Unmapped []tool.Tool
+}
+
+// MigrateTo moves tools to the given dependencies management tool, and rebuilds them.
+// Only migration from dep to Modules is supported.
+func (c *Config) MigrateTo(ctx context.Context, to manager.Type) (*MigrationResult, error) {
+	c.setDefaultsIfNeeded()
+
+	if c.ManagerType != manager.TypeDep || to != manager.TypeModules {
+		return nil, errors.Errorf("migration from %s to %s is not supported", c.ManagerType, to)
+	}
+
+	toolCfg := c.toolConfig()
+	if err := toolCfg.RequireManifest(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	m, err := tool.NewParser(c.FS, to).Parse(toolCfg.ManifestPath())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse the manifest file")
+	}
+	lock, err := dep.ReadLock(c.FS, c.RootDir)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// modules are disabled in GOPATH on Go 1.12 and older unless GO111MODULE=on
+	modEnv := append(append([]string{}, c.Env...), "GO111MODULE=on")
+	env := manager.Environ(os.Environ(), toolCfg.BinDir(), append(c.environ(), modEnv...)...)
+	executor := manager.NewExecutorWithEnv(c.Exec, c.OutWriter, c.ErrWriter, c.InReader, c.RootDir, env, c.Logger)
+
+	// files of dep are kept until the migration succeeds
+	backupDir := filepath.Join(toolCfg.BinDir(), ".dep")
+	backups := make(map[string]string)
+	fail := func(err error) error {
+		if rerr := c.restore(backups); rerr != nil {
+			c.Logger.Println("failed to restore files of dep:", rerr)
+		} else {
+			c.FS.RemoveAll(backupDir)
+		}
+		return err
+	}
+
+	// the vendor directory of dep has no modules.txt, and the go command fails with "inconsistent vendoring" since Go 1.14 while it exists
+	err = c.moveAside(backups, filepath.Join(c.RootDir, "vendor"), filepath.Join(backupDir, "vendor"))
+	if err != nil {
+		return nil, fail(errors.Wrap(err, "failed to move the vendor directory of dep"))
+	}
+
+	if ok, err := afero.Exists(c.FS, filepath.Join(c.RootDir, "go.mod")); err != nil {
+		return nil, fail(errors.WithStack(err))
+	} else if !ok {
+		err = executor.Exec(ctx, "go", "mod", "init")
+		if err != nil {
+			return nil, fail(errors.Wrap(err, "failed to initialize go.mod"))
+		}
+	}
+
+	var (
+		result = new(MigrationResult)
+		pkgs   = make([]string, 0, len(m.Tools()))
+	)
+
+	for _, t := range m.Tools() {
+		pkgs = append(pkgs, string(t))
+
+		p, ok := lock.FindProject(string(t))
+		if !ok {
+			c.Logger.Println(t, "is not found in Gopkg.lock")
+			result.Unmapped = append(result.Unmapped, t)
+			continue
+		}
+		err = executor.Exec(ctx, "go", "get", "-d", p.Name+"@"+p.ModuleQuery())
+		if err != nil {
+			c.Logger.Println("failed to require", p.Name, err)
+			result.Unmapped = append(result.Unmapped, t)
+			continue
+		}
+		result.Migrated = append(result.Migrated, t)
+	}
+
+	modCfg := *c
+	modCfg.ManagerType = to
+	modCfg.Env = modEnv
+	repo, err := modCfg.Create()
+	if err != nil {
+		return nil, fail(errors.WithStack(err))
+	}
+
+	// binaries built with dep should be rebuilt
+	for _, t := range m.Tools() {
+		err = c.moveAside(backups, toolCfg.BinPath(t.Name()), filepath.Join(backupDir, t.Name()))
+		if err != nil {
+			return result, fail(errors.Wrap(err, "failed to move binaries built with dep"))
+		}
+	}
+
+	// regenerate the manifest, tidy go.mod and build tools
+	err = repo.Add(ctx, pkgs...)
+	if err != nil {
+		return result, fail(errors.WithStack(err))
+	}
+
+	return result, errors.WithStack(c.FS.RemoveAll(backupDir))
+}
+
+// moveAside moves src to dest if it exists, and records their paths into moved.
+func (c *Config) moveAside(moved map[string]string, src, dest string) error {
+	if ok, err := afero.Exists(c.FS, src); err != nil {
+		return errors.WithStack(err)
+	} else if !ok {
+		return nil
+	}
+	err := c.FS.MkdirAll(filepath.Dir(dest), 0755)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = c.FS.Rename(src, dest)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	moved[src] = dest
+	return nil
+}
+
+// restore moves files back to their original paths, replacing files created by the failed migration.
+func (c *Config) restore(moved map[string]string) error {
+	for src, dest := range moved {
+		err := c.FS.RemoveAll(src)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		err = c.FS.Rename(dest, src)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package gex_test
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+
+	"github.com/izumin5210/gex"
+	"github.com/izumin5210/gex/pkg/manager"
+	"github.com/izumin5210/gex/pkg/tool"
+)
+
+func TestConfig_MigrateTo(t *testing.T) {
+	rootDir := filepath.FromSlash("/go/src/awesomeapp")
+
+	cases := []struct {
+		test       string
+		vendor     bool
+		fail       string
+		wantErr    bool
+		wantBin    string
+		wantVendor bool
+	}{
+		{
+			test:    "success",
+			wantBin: "bin",
+		},
+		{
+			test:    "build failure",
+			fail:    "build",
+			wantErr: true,
+			wantBin: "built with dep",
+		},
+		{
+			test:    "with vendor",
+			vendor:  true,
+			wantBin: "bin",
+		},
+		{
+			test:       "build failure with vendor",
+			vendor:     true,
+			fail:       "build",
+			wantErr:    true,
+			wantBin:    "built with dep",
+			wantVendor: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			// MemMapFs does not move files in renamed directories
+			tmpDir, err := ioutil.TempDir("", "gex-migrate")
+			if err != nil {
+				t.Fatalf("failed to create a temporary directory: %v", err)
+			}
+			defer os.RemoveAll(tmpDir)
+			fs := afero.NewBasePathFs(afero.NewOsFs(), tmpDir)
+			files := map[string]string{
+				"Gopkg.toml": "",
+				"Gopkg.lock": `[[projects]]
+  name = "github.com/golang/mock"
+  packages = ["mockgen"]
+  revision = "51421b967af1f557f93a59e0057aaf15ca02e29c"
+  version = "v1.2.0"
+`,
+				"go.mod":      "module awesomeapp\n",
+				"bin/mockgen": "built with dep",
+			}
+			if tc.vendor {
+				files["vendor/github.com/golang/mock/mockgen/main.go"] = "package main\n"
+			}
+			for name, body := range files {
+				path := filepath.Join(rootDir, filepath.FromSlash(name))
+				err = fs.MkdirAll(filepath.Dir(path), 0755)
+				if err == nil {
+					err = afero.WriteFile(fs, path, []byte(body), 0755)
+				}
+				if err != nil {
+					t.Fatalf("failed to write %s: %v", name, err)
+				}
+			}
+			err = tool.NewWriter(fs).Write(filepath.Join(rootDir, "tools.go"), tool.NewManifest([]tool.Tool{"github.com/golang/mock/mockgen"}, manager.TypeDep))
+			if err != nil {
+				t.Fatalf("failed to write the manifest: %v", err)
+			}
+
+			g := &fakeGo{fs: fs, gomod: filepath.Join(rootDir, "go.mod"), fail: tc.fail}
+			cfg := &gex.Config{
+				FS:          fs,
+				Exec:        g.exec(),
+				WorkingDir:  rootDir,
+				RootDir:     rootDir,
+				ManagerType: manager.TypeDep,
+				Logger:      log.New(ioutil.Discard, "", 0),
+			}
+
+			result, err := cfg.MigrateTo(context.Background(), manager.TypeModules)
+			if got, want := err != nil, tc.wantErr; got != want {
+				t.Fatalf("MigrateTo() returned %v, want error: %t", err, want)
+			}
+			if got, want := len(result.Migrated), 1; got != want {
+				t.Errorf("MigrateTo() migrated %d tools, want %d", got, want)
+			}
+
+			got, err := afero.ReadFile(fs, filepath.Join(rootDir, "bin", "mockgen"))
+			if err != nil {
+				t.Fatalf("failed to read the binary: %v", err)
+			}
+			if string(got) != tc.wantBin {
+				t.Errorf("the binary is %q, want %q", got, tc.wantBin)
+			}
+			if ok, _ := afero.Exists(fs, filepath.Join(rootDir, "bin", ".dep")); ok {
+				t.Errorf("moved files are left")
+			}
+			// the vendor directory of dep is removed after the migration succeeds
+			vendored, _ := afero.Exists(fs, filepath.Join(rootDir, "vendor", "github.com", "golang", "mock", "mockgen", "main.go"))
+			if got, want := vendored, tc.wantVendor; got != want {
+				t.Errorf("the vendor directory exists: %t, want %t", got, want)
+			}
+		})
+	}
+}
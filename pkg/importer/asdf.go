@@ -0,0 +1,61 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+// asdfPlugins maps names of asdf plugins to packages of Go commands.
+var asdfPlugins = map[string]string{
+	"buf":           "github.com/bufbuild/buf/cmd/buf",
+	"ghq":           "github.com/x-motemen/ghq",
+	"gofumpt":       "mvdan.cc/gofumpt",
+	"golangci-lint": "github.com/golangci/golangci-lint/cmd/golangci-lint",
+	"goreleaser":    "github.com/goreleaser/goreleaser",
+	"gotestsum":     "gotest.tools/gotestsum",
+	"protoc-gen-go": "github.com/golang/protobuf/protoc-gen-go",
+	"reviewdog":     "github.com/reviewdog/reviewdog/cmd/reviewdog",
+	"shfmt":         "mvdan.cc/sh/v3/cmd/shfmt",
+}
+
+// asdfImporter reads .tool-versions managed by https://github.com/asdf-vm/asdf.
+// asdf manages tools by plugin names, so only known plugins are mapped to Go packages.
+type asdfImporter struct{}
+
+func (*asdfImporter) DefaultPath() string { return ".tool-versions" }
+
+func (*asdfImporter) Import(fs afero.Fs, path string) (*Result, error) {
+	data, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	result := new(Result)
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := sc.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		pkg, ok := asdfPlugins[fields[0]]
+		if !ok || len(fields) < 2 {
+			result.Unmapped = append(result.Unmapped, strings.Join(fields, " "))
+			continue
+		}
+		// asdf allows fallback versions, and the first one has precedence.
+		result.Packages = append(result.Packages, Package{Path: pkg, Version: normalizeVersion(fields[1])})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return result, nil
+}
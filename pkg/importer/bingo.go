@@ -0,0 +1,93 @@
+package importer
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+// bingoImporter reads .mod files in the .bingo directory managed by https://github.com/bwplotka/bingo.
+// Each file requires a module, and a package path in the module is written in the line comment.
+//
+//	require github.com/golangci/golangci-lint v1.27.0 // cmd/golangci-lint
+type bingoImporter struct{}
+
+func (*bingoImporter) DefaultPath() string { return ".bingo" }
+
+func (i *bingoImporter) Import(fs afero.Fs, p string) (*Result, error) {
+	files := []string{p}
+	if ok, err := afero.IsDir(fs, p); err != nil {
+		return nil, errors.WithStack(err)
+	} else if ok {
+		files, err = afero.Glob(fs, filepath.Join(p, "*.mod"))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	result := new(Result)
+	for _, f := range files {
+		// go.mod in .bingo is a placeholder to exclude the directory from the main module
+		if filepath.Base(f) == "go.mod" {
+			continue
+		}
+		data, err := afero.ReadFile(fs, f)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		pkgs := parseBingoMod(string(data))
+		if len(pkgs) == 0 {
+			result.Unmapped = append(result.Unmapped, f)
+		}
+		result.Packages = append(result.Packages, pkgs...)
+	}
+
+	if len(files) == 0 {
+		return nil, errors.Errorf("%s does not contain .mod files", p)
+	}
+
+	return result, nil
+}
+
+func parseBingoMod(data string) []Package {
+	var (
+		pkgs    []Package
+		inBlock bool
+	)
+
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "require ("):
+			inBlock = true
+			continue
+		case inBlock && line == ")":
+			inBlock = false
+			continue
+		case strings.HasPrefix(line, "require "):
+			line = strings.TrimPrefix(line, "require ")
+		case !inBlock:
+			continue
+		}
+
+		var comment string
+		if i := strings.Index(line, "//"); i >= 0 {
+			comment = strings.TrimSpace(line[i+2:])
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		pkg := Package{Path: fields[0], Version: fields[1]}
+		if comment != "" && comment != "indirect" {
+			pkg.Path = path.Join(pkg.Path, comment)
+		}
+		pkgs = append(pkgs, pkg)
+	}
+
+	return pkgs
+}
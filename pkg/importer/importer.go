@@ -0,0 +1,73 @@
+package importer
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+// Package represents a tool package declared by another tool manager.
+type Package struct {
+	Path    string
+	Version string
+}
+
+// String returns the package in a form that can be passed to `gex --add`.
+func (p Package) String() string {
+	if p.Version == "" {
+		return p.Path
+	}
+	return p.Path + "@" + p.Version
+}
+
+// Result contains tools read by an Importer.
+type Result struct {
+	Packages []Package
+	// Unmapped contains declarations that could not be mapped to Go packages.
+	Unmapped []string
+}
+
+// Importer reads tools declared by another tool manager.
+type Importer interface {
+	// DefaultPath returns a path of the file (or the directory) that the tool manager uses.
+	DefaultPath() string
+	Import(fs afero.Fs, path string) (*Result, error)
+}
+
+var importers = map[string]Importer{
+	"bingo": &bingoImporter{},
+	"asdf":  &asdfImporter{},
+	"make":  &makeImporter{},
+	"txt":   &txtImporter{},
+}
+
+// Lookup returns an Importer for the format.
+func Lookup(format string) (Importer, error) {
+	imp, ok := importers[format]
+	if !ok {
+		return nil, errors.Errorf("unknown format %q, it should be one of %s", format, strings.Join(Formats(), ", "))
+	}
+	return imp, nil
+}
+
+// Formats returns names of supported formats.
+func Formats() []string {
+	formats := make([]string, 0, len(importers))
+	for f := range importers {
+		formats = append(formats, f)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+func normalizeVersion(v string) string {
+	if v == "" || strings.HasPrefix(v, "v") || v == "latest" {
+		return v
+	}
+	if c := v[0]; '0' <= c && c <= '9' {
+		return "v" + v
+	}
+	return v
+}
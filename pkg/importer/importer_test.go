@@ -0,0 +1,146 @@
+package importer_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+
+	"github.com/izumin5210/gex/pkg/importer"
+)
+
+func TestImporter_Import(t *testing.T) {
+	cases := []struct {
+		test   string
+		format string
+		files  map[string]string
+		path   string
+		want   *importer.Result
+	}{
+		{
+			test:   "bingo",
+			format: "bingo",
+			files: map[string]string{
+				"/app/.bingo/go.mod": "module _ // Fake go.mod auto-created by 'bingo' for go -moddir compatibility with non-Go projects. Commit this file, together with other .mod files.\n",
+				"/app/.bingo/golangci-lint.mod": `module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+require github.com/golangci/golangci-lint v1.27.0 // cmd/golangci-lint
+`,
+				"/app/.bingo/goimports.mod": `module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+require (
+	golang.org/x/tools v0.0.0-20200619180055-7c3f7b1d8d3e // cmd/goimports
+)
+`,
+				"/app/.bingo/empty.mod": "module _\n",
+			},
+			path: "/app/.bingo",
+			want: &importer.Result{
+				Packages: []importer.Package{
+					{Path: "golang.org/x/tools/cmd/goimports", Version: "v0.0.0-20200619180055-7c3f7b1d8d3e"},
+					{Path: "github.com/golangci/golangci-lint/cmd/golangci-lint", Version: "v1.27.0"},
+				},
+				Unmapped: []string{"/app/.bingo/empty.mod"},
+			},
+		},
+		{
+			test:   "asdf",
+			format: "asdf",
+			files: map[string]string{
+				"/app/.tool-versions": `golang 1.14.4
+golangci-lint 1.27.0 1.26.0 # fallback
+nodejs 12.18.0
+`,
+			},
+			path: "/app/.tool-versions",
+			want: &importer.Result{
+				Packages: []importer.Package{
+					{Path: "github.com/golangci/golangci-lint/cmd/golangci-lint", Version: "v1.27.0"},
+				},
+				Unmapped: []string{"golang 1.14.4", "nodejs 12.18.0"},
+			},
+		},
+		{
+			test:   "make",
+			format: "make",
+			files: map[string]string{
+				"/app/Makefile": `MOCKGEN_VERSION := v1.4.3
+
+.PHONY: tools
+tools:
+	go install github.com/golang/mock/mockgen@$(MOCKGEN_VERSION)
+	cd /tmp && GO111MODULE=on go get -v golang.org/x/lint/golint@latest
+	@go install github.com/izumin5210/gex/cmd/gex@v0.6.0; echo done
+	go install ./cmd/app
+`,
+			},
+			path: "/app/Makefile",
+			want: &importer.Result{
+				Packages: []importer.Package{
+					{Path: "golang.org/x/lint/golint", Version: "latest"},
+					{Path: "github.com/izumin5210/gex/cmd/gex", Version: "v0.6.0"},
+				},
+				Unmapped: []string{"go install github.com/golang/mock/mockgen@$(MOCKGEN_VERSION)"},
+			},
+		},
+		{
+			test:   "txt",
+			format: "txt",
+			files: map[string]string{
+				"/app/tools.txt": `# tools
+github.com/golang/mock/mockgen@v1.4.3
+golang.org/x/lint/golint
+github.com/izumin5210/gex/cmd/gex v0.6.0
+
+github.com/foo/bar v1 v2
+`,
+			},
+			path: "/app/tools.txt",
+			want: &importer.Result{
+				Packages: []importer.Package{
+					{Path: "github.com/golang/mock/mockgen", Version: "v1.4.3"},
+					{Path: "golang.org/x/lint/golint"},
+					{Path: "github.com/izumin5210/gex/cmd/gex", Version: "v0.6.0"},
+				},
+				Unmapped: []string{"github.com/foo/bar v1 v2"},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			for path, data := range tc.files {
+				err := afero.WriteFile(fs, path, []byte(data), 0644)
+				if err != nil {
+					t.Fatalf("failed to write %s: %v", path, err)
+				}
+			}
+
+			imp, err := importer.Lookup(tc.format)
+			if err != nil {
+				t.Fatalf("Lookup() returned an error: %v", err)
+			}
+
+			got, err := imp.Import(fs, tc.path)
+			if err != nil {
+				t.Fatalf("Import() returned an error: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Import() returned unexpected result (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLookup_Unknown(t *testing.T) {
+	_, err := importer.Lookup("brew")
+	if err == nil {
+		t.Error("Lookup() should return an error")
+	}
+}
@@ -0,0 +1,51 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+var goInstallPattern = regexp.MustCompile(`\bgo\s+(?:install|get)\s+((?:-\S+\s+)*)(\S+@\S+)`)
+
+// makeImporter reads `go install pkg@version` (or `go get pkg@version`) lines in a Makefile.
+type makeImporter struct{}
+
+func (*makeImporter) DefaultPath() string { return "Makefile" }
+
+func (*makeImporter) Import(fs afero.Fs, path string) (*Result, error) {
+	data, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	result := new(Result)
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		for _, m := range goInstallPattern.FindAllStringSubmatch(sc.Text(), -1) {
+			arg := strings.TrimRight(m[2], ";)")
+			// Packages or versions declared with make variables cannot be resolved statically.
+			if strings.Contains(arg, "$") {
+				result.Unmapped = append(result.Unmapped, strings.TrimSpace(m[0]))
+				continue
+			}
+			result.Packages = append(result.Packages, parsePackage(arg))
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return result, nil
+}
+
+func parsePackage(s string) Package {
+	if i := strings.LastIndex(s, "@"); i >= 0 {
+		return Package{Path: s[:i], Version: s[i+1:]}
+	}
+	return Package{Path: s}
+}
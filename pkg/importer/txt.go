@@ -0,0 +1,48 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+// txtImporter reads a plain text file that lists a package per line.
+// A version can be specified with `pkg@version` or `pkg version`, and lines starting with `#` are ignored.
+type txtImporter struct{}
+
+func (*txtImporter) DefaultPath() string { return "tools.txt" }
+
+func (*txtImporter) Import(fs afero.Fs, path string) (*Result, error) {
+	data, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	result := new(Result)
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := sc.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+			continue
+		case len(fields) == 1:
+			result.Packages = append(result.Packages, parsePackage(fields[0]))
+		case len(fields) == 2 && !strings.Contains(fields[0], "@"):
+			result.Packages = append(result.Packages, Package{Path: fields[0], Version: fields[1]})
+		default:
+			result.Unmapped = append(result.Unmapped, strings.Join(fields, " "))
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,44 @@
+package manager
+
+import (
+	"path/filepath"
+
+	"github.com/spf13/afero"
+)
+
+// builtinBackends returns hints of built-in backends.
+// They are registered in this package, so that Types of built-in backends work without importing the backend packages,
+// which complete them with Detect and New.
+func builtinBackends() map[Type]*Backend {
+	return map[Type]*Backend{
+		TypeModules: {
+			Name:       "mod",
+			Priority:   10,
+			BuildFlags: modBuildFlags,
+			LockFiles:  []string{"go.mod", "go.sum", "vendor/modules.txt"},
+		},
+		TypeDep: {
+			Name:      "dep",
+			Priority:  20,
+			Vendor:    true,
+			LockFiles: []string{"Gopkg.toml", "Gopkg.lock"},
+		},
+		TypeGOPATH: {
+			Name: "gopath",
+		},
+	}
+}
+
+// ModVendored returns true if dependencies of the module in rootDir are vendored with `go mod vendor`.
+func ModVendored(fs afero.Fs, rootDir string) bool {
+	ok, _ := afero.Exists(fs, filepath.Join(rootDir, "vendor", "modules.txt"))
+	return ok
+}
+
+// modBuildFlags builds tools from the vendor directory if the module is vendored with `go mod vendor`.
+func modBuildFlags(fs afero.Fs, rootDir string) []string {
+	if ModVendored(fs, rootDir) {
+		return []string{"-mod=vendor"}
+	}
+	return nil
+}
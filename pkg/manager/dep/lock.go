@@ -0,0 +1,83 @@
+package dep
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+// LockedProject represents a project pinned in Gopkg.lock.
+type LockedProject struct {
+	Name     string   `toml:"name"`
+	Branch   string   `toml:"branch"`
+	Version  string   `toml:"version"`
+	Revision string   `toml:"revision"`
+	Packages []string `toml:"packages"`
+}
+
+// ModuleQuery returns a version query for `go get` that points the pinned revision.
+// A semantic version tag is preferred if the project is pinned with it.
+func (p *LockedProject) ModuleQuery() string {
+	v := p.Version
+	if v != "" && !strings.HasPrefix(v, "v") {
+		v = "v" + v
+	}
+	if isSemver(v) {
+		return v
+	}
+	return p.Revision
+}
+
+// Lock represents contents of Gopkg.lock.
+type Lock struct {
+	Projects []*LockedProject `toml:"projects"`
+}
+
+// FindProject returns a project that provides the package.
+func (l *Lock) FindProject(pkg string) (*LockedProject, bool) {
+	for root := pkg; root != "." && root != "/"; root = path.Dir(root) {
+		for _, p := range l.Projects {
+			if p.Name == root {
+				return p, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// ReadLock reads Gopkg.lock in rootDir.
+func ReadLock(fs afero.Fs, rootDir string) (*Lock, error) {
+	lockPath := filepath.Join(rootDir, "Gopkg.lock")
+	data, err := afero.ReadFile(fs, lockPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", lockPath)
+	}
+
+	var lock Lock
+	_, err = toml.Decode(string(data), &lock)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", lockPath)
+	}
+
+	return &lock, nil
+}
+
+func isSemver(v string) bool {
+	if !strings.HasPrefix(v, "v") {
+		return false
+	}
+	parts := strings.SplitN(strings.SplitN(v[1:], "-", 2)[0], ".", 3)
+	if len(parts) != 3 {
+		return false
+	}
+	for _, p := range parts {
+		if p == "" || strings.Trim(p, "0123456789") != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,84 @@
+package dep_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+
+	"github.com/izumin5210/gex/pkg/manager/dep"
+)
+
+func TestReadLock(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	rootDir := "/go/src/awesomeapp"
+
+	lockToml := `# This file is autogenerated, do not edit; changes may be undone by the next 'dep ensure'.
+
+[[projects]]
+  digest = "1:e9d4e5a0a6b2da1e0e0cd8e5d5ff5f0a2a6a0ddc3f4bcbd2c7f3c5b0a5a9b3ad"
+  name = "github.com/golang/mock"
+  packages = [
+    "mockgen",
+    "mockgen/model",
+  ]
+  pruneopts = "UT"
+  revision = "51421b967af1f557f93a59e0057aaf15ca02e29c"
+  version = "v1.2.0"
+
+[[projects]]
+  branch = "master"
+  name = "golang.org/x/lint"
+  packages = ["golint"]
+  revision = "fdd1cda4f05fd1fd86124f0ef9ce31a0b72c8448"
+
+[[projects]]
+  name = "github.com/spf13/pflag"
+  packages = ["."]
+  revision = "298182f68c66c05229eb03ac171abe6e309ee79a"
+  version = "1.0.3"
+
+[solve-meta]
+  analyzer-name = "dep"
+  analyzer-version = 1
+`
+	err := afero.WriteFile(fs, rootDir+"/Gopkg.lock", []byte(lockToml), 0644)
+	if err != nil {
+		t.Fatalf("failed to write Gopkg.lock: %v", err)
+	}
+
+	lock, err := dep.ReadLock(fs, rootDir)
+	if err != nil {
+		t.Fatalf("ReadLock() returned an error: %v", err)
+	}
+
+	cases := []struct {
+		pkg   string
+		name  string
+		query string
+	}{
+		{pkg: "github.com/golang/mock/mockgen", name: "github.com/golang/mock", query: "v1.2.0"},
+		{pkg: "golang.org/x/lint/golint", name: "golang.org/x/lint", query: "fdd1cda4f05fd1fd86124f0ef9ce31a0b72c8448"},
+		{pkg: "github.com/spf13/pflag", name: "github.com/spf13/pflag", query: "v1.0.3"},
+		{pkg: "github.com/volatiletech/sqlboiler"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.pkg, func(t *testing.T) {
+			p, ok := lock.FindProject(tc.pkg)
+
+			if got, want := ok, tc.name != ""; got != want {
+				t.Fatalf("FindProject() returned %t, want %t", got, want)
+			}
+			if !ok {
+				return
+			}
+			if diff := cmp.Diff(tc.name, p.Name); diff != "" {
+				t.Errorf("project name differs: (-want +got)\n%s", diff)
+			}
+			if got, want := p.ModuleQuery(), tc.query; got != want {
+				t.Errorf("ModuleQuery() returned %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -7,15 +7,40 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/izumin5210/execx"
 	"github.com/pkg/errors"
+	"github.com/spf13/afero"
 
 	"github.com/izumin5210/gex/pkg/manager"
 )
 
+func init() {
+	manager.Register(manager.TypeDep, &manager.Backend{
+		Detect: Detect,
+		New: func(opts *manager.Options) manager.Interface {
+			return newManager(opts.Executor, opts.FS, opts.RootDir, opts.WorkingDir)
+		},
+	})
+}
+
+// Detect returns a root directory of the project that has Gopkg.toml.
+func Detect(workDir string, fs afero.Fs, _ *execx.Executor) (string, bool) {
+	root, err := manager.FindRoot(workDir, fs, "Gopkg.toml")
+	return root, err == nil
+}
+
 // NewManager creates a manager.Interface instance to manage tools vendored with dep.
 func NewManager(executor manager.Executor, rootDir, workingDir string) manager.Interface {
+	return newManager(executor, afero.NewOsFs(), rootDir, workingDir)
+}
+
+func newManager(executor manager.Executor, fs afero.Fs, rootDir, workingDir string) manager.Interface {
+	if fs == nil {
+		fs = afero.NewOsFs()
+	}
 	return &managerImpl{
 		executor:   executor,
+		fs:         fs,
 		rootDir:    rootDir,
 		workingDir: workingDir,
 	}
@@ -23,6 +48,7 @@ func NewManager(executor manager.Executor, rootDir, workingDir string) manager.I
 
 type managerImpl struct {
 	executor   manager.Executor
+	fs         afero.Fs
 	rootDir    string
 	workingDir string
 }
@@ -47,7 +73,7 @@ func (m *managerImpl) Add(ctx context.Context, pkgs []string, verbose bool) erro
 	return errors.WithStack(m.executor.Exec(ctx, "dep", args...))
 }
 
-func (m *managerImpl) Build(ctx context.Context, binPath, pkg string, verbose bool) error {
+func (m *managerImpl) Build(ctx context.Context, binPath, pkg string, opts manager.BuildOptions) error {
 	target, err := filepath.Rel(m.workingDir, m.rootDir)
 	if err != nil {
 		return errors.WithStack(err)
@@ -57,11 +83,12 @@ func (m *managerImpl) Build(ctx context.Context, binPath, pkg string, verbose bo
 		target = "." + string(filepath.Separator) + target
 	}
 	args := []string{"build", "-o", binPath}
-	if verbose {
+	if opts.Verbose {
 		args = append(args, "-v")
 	}
+	args = append(args, opts.Flags...)
 	args = append(args, target)
-	return errors.WithStack(m.executor.Exec(ctx, "go", args...))
+	return errors.WithStack(manager.ExecutorWithStderr(m.executor, opts.Stderr).Exec(ctx, "go", args...))
 }
 
 func (m *managerImpl) Sync(ctx context.Context, verbose bool) error {
@@ -72,6 +99,54 @@ func (m *managerImpl) Sync(ctx context.Context, verbose bool) error {
 	return errors.WithStack(m.executor.Exec(ctx, "dep", args...))
 }
 
+func (m *managerImpl) Download(ctx context.Context, pkgs []string, verbose bool) error {
+	args := []string{"ensure", "-vendor-only"}
+	if verbose {
+		args = append(args, "-v")
+	}
+	return errors.WithStack(m.executor.Exec(ctx, "dep", args...))
+}
+
+func (m *managerImpl) Versions(ctx context.Context, pkgs []string) (map[string]string, error) {
+	projects, err := m.getProjects(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	revs := make(map[string]string, len(projects))
+	for _, p := range projects {
+		revs[p.ProjectRoot] = p.Revision
+	}
+
+	versions := make(map[string]string, len(pkgs))
+	for _, pkg := range pkgs {
+		for root := pkg; root != "."; root = path.Dir(root) {
+			if rev, ok := revs[root]; ok {
+				versions[pkg] = rev
+				break
+			}
+		}
+	}
+
+	return versions, nil
+}
+
+// PinnedVersions reads revisions of projects that provide given packages from Gopkg.lock.
+func (m *managerImpl) PinnedVersions(ctx context.Context, pkgs []string) (map[string]string, error) {
+	lock, err := ReadLock(m.fs, m.rootDir)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	versions := make(map[string]string, len(pkgs))
+	for _, pkg := range pkgs {
+		if p, ok := lock.FindProject(pkg); ok {
+			versions[pkg] = p.Revision
+		}
+	}
+	return versions, nil
+}
+
 func (m *managerImpl) pickNewPackages(ctx context.Context, pkgs []string) ([]string, error) {
 	pkgSet, err := m.getExistingPackageSet(ctx)
 	if err != nil {
@@ -105,8 +180,7 @@ func (m *managerImpl) getExistingPackageSet(ctx context.Context) (map[string]str
 	if err != nil {
 		return make(map[string]struct{}), nil
 	}
-	pkgs := []struct{ ProjectRoot string }{}
-	err = json.Unmarshal(out, &pkgs)
+	pkgs, err := parseProjects(out)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
@@ -118,3 +192,25 @@ func (m *managerImpl) getExistingPackageSet(ctx context.Context) (map[string]str
 
 	return pkgRoots, nil
 }
+
+type project struct {
+	ProjectRoot string
+	Revision    string
+}
+
+func (m *managerImpl) getProjects(ctx context.Context) ([]project, error) {
+	out, err := m.executor.Output(ctx, "dep", "status", "-json")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return parseProjects(out)
+}
+
+func parseProjects(data []byte) ([]project, error) {
+	var projects []project
+	err := json.Unmarshal(data, &projects)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return projects, nil
+}
@@ -0,0 +1,62 @@
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Environ builds environment variables for processes executed by gex.
+// binDir is prepended to PATH, and extra variables given as "KEY=value" override ones in base.
+// Each variable appears only once in the result.
+func Environ(base []string, binDir string, extra ...string) []string {
+	var (
+		env   = make([]string, 0, len(base)+len(extra))
+		index = make(map[string]int, len(base)+len(extra))
+	)
+
+	for _, kv := range append(append([]string{}, base...), extra...) {
+		k := envKey(kv)
+		if i, ok := index[k]; ok {
+			env[i] = kv
+			continue
+		}
+		index[k] = len(env)
+		env = append(env, kv)
+	}
+
+	if binDir != "" {
+		if i, ok := index[envKey("PATH=")]; ok {
+			kv := strings.SplitN(env[i], "=", 2)
+			env[i] = kv[0] + "=" + PrependPath(kv[1], binDir)
+		} else {
+			env = append(env, "PATH="+filepath.Clean(binDir))
+		}
+	}
+
+	return env
+}
+
+// PrependPath returns a list of directories like PATH that has dir at the head.
+// Entries equivalent to dir are removed from the rest.
+func PrependPath(path, dir string) string {
+	dir = filepath.Clean(dir)
+	entries := []string{dir}
+	for _, e := range filepath.SplitList(path) {
+		if e == "" || filepath.Clean(e) == dir {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return strings.Join(entries, string(os.PathListSeparator))
+}
+
+func envKey(kv string) string {
+	k := strings.SplitN(kv, "=", 2)[0]
+	if runtime.GOOS == "windows" {
+		// environment variables are case-insensitive on Windows
+		k = strings.ToUpper(k)
+	}
+	return k
+}
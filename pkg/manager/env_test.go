@@ -0,0 +1,69 @@
+package manager_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/izumin5210/gex/pkg/manager"
+)
+
+func TestEnviron(t *testing.T) {
+	path := func(dirs ...string) string {
+		return "PATH=" + strings.Join(dirs, string(os.PathListSeparator))
+	}
+	rootDir := filepath.FromSlash("/go/src/awesomeapp")
+	binDir := filepath.Join(rootDir, "bin")
+
+	cases := []struct {
+		test   string
+		base   []string
+		binDir string
+		extra  []string
+		want   []string
+	}{
+		{
+			test:   "prepend bin dir",
+			base:   []string{"HOME=/home/gopher", path("/usr/local/bin", "/usr/bin")},
+			binDir: binDir,
+			want:   []string{"HOME=/home/gopher", path(binDir, "/usr/local/bin", "/usr/bin")},
+		},
+		{
+			test:   "bin dir already in PATH",
+			base:   []string{path("/usr/bin", binDir+string(filepath.Separator))},
+			binDir: binDir,
+			want:   []string{path(binDir, "/usr/bin")},
+		},
+		{
+			test:   "no PATH",
+			base:   []string{"HOME=/home/gopher"},
+			binDir: binDir,
+			want:   []string{"HOME=/home/gopher", path(binDir)},
+		},
+		{
+			test: "duplicated variables",
+			base: []string{path("/usr/bin"), "GOFLAGS=-v", "HOME=/home/gopher", path("/bin")},
+			want: []string{path("/bin"), "GOFLAGS=-v", "HOME=/home/gopher"},
+		},
+		{
+			test:   "extra variables",
+			base:   []string{"GOFLAGS=-v", path("/usr/bin")},
+			binDir: binDir,
+			extra:  []string{"GOPROXY=off", "GOFLAGS=-mod=mod"},
+			want:   []string{"GOFLAGS=-mod=mod", path(binDir, "/usr/bin"), "GOPROXY=off"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			got := manager.Environ(tc.base, tc.binDir, tc.extra...)
+
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("environment variables differ: (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
@@ -19,15 +19,15 @@ type Executor interface {
 }
 
 // NewExecutor creates a new Executor instance.
-func NewExecutor(exec *execx.Executor, outW, errW io.Writer, inR io.Reader, cwd string, log *log.Logger) Executor {
-	env := os.Environ()
-	for _, e := range env {
-		kv := strings.SplitN(e, "=", 2)
-		if kv[0] == "PATH" {
-			kv[1] = filepath.Join(cwd, "bin") + string(os.PathListSeparator) + kv[1]
-		}
-		env = append(env, strings.Join(kv, "="))
-	}
+// Commands are executed with the current environment and cwd/bin in PATH.
+// Additional environment variables can be given as "KEY=value" strings.
+func NewExecutor(exec *execx.Executor, outW, errW io.Writer, inR io.Reader, cwd string, log *log.Logger, extraEnv ...string) Executor {
+	env := Environ(os.Environ(), filepath.Join(cwd, "bin"), extraEnv...)
+	return NewExecutorWithEnv(exec, outW, errW, inR, cwd, env, log)
+}
+
+// NewExecutorWithEnv creates a new Executor instance that executes commands with env, that can be built with Environ.
+func NewExecutorWithEnv(exec *execx.Executor, outW, errW io.Writer, inR io.Reader, cwd string, env []string, log *log.Logger) Executor {
 	return &executorImpl{
 		exec: exec,
 		outW: outW,
@@ -39,6 +39,30 @@ func NewExecutor(exec *execx.Executor, outW, errW io.Writer, inR io.Reader, cwd
 	}
 }
 
+// ExecutorWithEnv returns a copy of e that executes commands with additional environment variables.
+// Executors that are not created by this package are returned as they are.
+func ExecutorWithEnv(e Executor, env ...string) Executor {
+	impl, ok := e.(*executorImpl)
+	if !ok || len(env) == 0 {
+		return e
+	}
+	newE := *impl
+	newE.env = Environ(impl.env, "", env...)
+	return &newE
+}
+
+// ExecutorWithStderr returns a copy of e that writes stderr of commands into w instead of its error writer.
+// Executors that are not created by this package, and e with nil w are returned as they are.
+func ExecutorWithStderr(e Executor, w io.Writer) Executor {
+	impl, ok := e.(*executorImpl)
+	if !ok || w == nil {
+		return e
+	}
+	newE := *impl
+	newE.errW = w
+	return &newE
+}
+
 type executorImpl struct {
 	exec       *execx.Executor
 	outW, errW io.Writer
@@ -0,0 +1,92 @@
+package manager_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os/exec"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/izumin5210/execx"
+
+	"github.com/izumin5210/gex/pkg/manager"
+)
+
+func TestExecutorWithEnv(t *testing.T) {
+	var got []string
+	fakeExec := execx.New(execx.WithFakeProcess(func(_ context.Context, cmd *exec.Cmd) error {
+		got = cmd.Env
+		return nil
+	}))
+	base := manager.NewExecutorWithEnv(fakeExec, ioutil.Discard, ioutil.Discard, nil, "/home/src/awesomeapp", []string{"PATH=/usr/bin", "FOO=1"}, log.New(ioutil.Discard, "", 0))
+	ctx := context.Background()
+
+	cases := []struct {
+		test     string
+		executor manager.Executor
+		want     []string
+	}{
+		{
+			test:     "base",
+			executor: base,
+			want:     []string{"PATH=/usr/bin", "FOO=1"},
+		},
+		{
+			test:     "with env",
+			executor: manager.ExecutorWithEnv(base, "FOO=2", "BAR=3"),
+			want:     []string{"PATH=/usr/bin", "FOO=2", "BAR=3"},
+		},
+		{
+			test:     "nested",
+			executor: manager.ExecutorWithEnv(manager.ExecutorWithEnv(base, "FOO=2"), "BAR=3"),
+			want:     []string{"PATH=/usr/bin", "FOO=2", "BAR=3"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			got = nil
+			if err := tc.executor.Exec(ctx, "go", "version"); err != nil {
+				t.Fatalf("Exec() returned an error: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Exec() executed a command with unexpected env: (-want +got)\n%s", diff)
+			}
+
+			got = nil
+			if _, err := tc.executor.Output(ctx, "go", "version"); err != nil {
+				t.Fatalf("Output() returned an error: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Output() executed a command with unexpected env: (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestExecutorWithStderr(t *testing.T) {
+	fakeExec := execx.New(execx.WithFakeProcess(func(_ context.Context, cmd *exec.Cmd) error {
+		fmt.Fprintln(cmd.Stderr, "WORK=/tmp/go-build")
+		return nil
+	}))
+	var errW, buildErrW bytes.Buffer
+	base := manager.NewExecutor(fakeExec, ioutil.Discard, &errW, nil, "/home/src/awesomeapp", log.New(ioutil.Discard, "", 0))
+	ctx := context.Background()
+
+	if err := manager.ExecutorWithStderr(base, &buildErrW).Exec(ctx, "go", "build", "-x"); err != nil {
+		t.Fatalf("Exec() returned an error: %v", err)
+	}
+	if err := manager.ExecutorWithStderr(base, nil).Exec(ctx, "go", "version"); err != nil {
+		t.Fatalf("Exec() returned an error: %v", err)
+	}
+
+	if got, want := buildErrW.String(), "WORK=/tmp/go-build\n"; got != want {
+		t.Errorf("stderr of the command with the writer is %q, want %q", got, want)
+	}
+	if got, want := errW.String(), "WORK=/tmp/go-build\n"; got != want {
+		t.Errorf("stderr of the command without the writer is %q, want %q", got, want)
+	}
+}
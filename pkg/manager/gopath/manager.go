@@ -0,0 +1,126 @@
+package gopath
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/izumin5210/gex/pkg/manager"
+)
+
+func init() {
+	// GOPATH mode is not detected, and used when any other tools are not detected.
+	manager.Register(manager.TypeGOPATH, &manager.Backend{
+		New: func(opts *manager.Options) manager.Interface {
+			return NewManager(opts.Executor, opts.ErrWriter)
+		},
+	})
+}
+
+// NewManager creates a manager.Interface instance to build tools at whatever versions are in GOPATH.
+// Warnings about unpinned versions are written into errW.
+func NewManager(executor manager.Executor, errW io.Writer) manager.Interface {
+	return &managerImpl{
+		executor: manager.ExecutorWithEnv(executor, "GO111MODULE=off"),
+		errW:     errW,
+	}
+}
+
+type managerImpl struct {
+	executor manager.Executor
+	errW     io.Writer
+	warnOnce sync.Once
+}
+
+func (m *managerImpl) Add(ctx context.Context, pkgs []string, verbose bool) error {
+	m.warn()
+	targets := make([]string, len(pkgs))
+	for i, pkg := range pkgs {
+		targets[i] = strings.SplitN(pkg, "@", 2)[0]
+		if targets[i] != pkg {
+			fmt.Fprintf(m.errW, "gex: warning: the version of %s is ignored in GOPATH mode\n", targets[i])
+		}
+	}
+	return errors.WithStack(m.get(ctx, targets, verbose))
+}
+
+func (m *managerImpl) Build(ctx context.Context, binPath, pkg string, opts manager.BuildOptions) error {
+	m.warn()
+	args := []string{"build", "-o", binPath}
+	if opts.Verbose {
+		args = append(args, "-v")
+	}
+	args = append(args, opts.Flags...)
+	args = append(args, pkg)
+	return errors.WithStack(manager.ExecutorWithStderr(m.executor, opts.Stderr).Exec(ctx, "go", args...))
+}
+
+func (m *managerImpl) Sync(ctx context.Context, verbose bool) error {
+	return nil
+}
+
+func (m *managerImpl) Download(ctx context.Context, pkgs []string, verbose bool) error {
+	return errors.WithStack(m.get(ctx, pkgs, verbose))
+}
+
+// get downloads packages that do not exist in GOPATH.
+func (m *managerImpl) get(ctx context.Context, pkgs []string, verbose bool) error {
+	pkgs, err := m.missingPackages(ctx, pkgs)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if len(pkgs) == 0 {
+		return nil
+	}
+
+	if ok, err := m.getSupported(ctx); err != nil {
+		return errors.WithStack(err)
+	} else if !ok {
+		return errors.Errorf("%s not found in GOPATH, and `go get` does not download packages in GOPATH mode since Go 1.22: clone them into GOPATH, or manage tools with Modules (`go mod init`)", strings.Join(pkgs, ", "))
+	}
+
+	args := []string{"get", "-d"}
+	if verbose {
+		args = append(args, "-v")
+	}
+	args = append(args, pkgs...)
+	return errors.WithStack(m.executor.Exec(ctx, "go", args...))
+}
+
+// missingPackages returns packages that can not be loaded from GOPATH.
+func (m *managerImpl) missingPackages(ctx context.Context, pkgs []string) ([]string, error) {
+	if len(pkgs) == 0 {
+		return nil, nil
+	}
+	args := append([]string{"list", "-e", "-f", "{{if .Error}}{{.ImportPath}}{{end}}"}, pkgs...)
+	out, err := m.executor.Output(ctx, "go", args...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// getSupported returns true if `go get` downloads packages in GOPATH mode, that is removed in Go 1.22.
+func (m *managerImpl) getSupported(ctx context.Context) (bool, error) {
+	out, err := m.executor.Output(ctx, "go", "env", "GOVERSION")
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	// GOVERSION is empty before Go 1.16, and "devel ..." for development versions
+	var minor int
+	if _, err := fmt.Sscanf(strings.TrimSpace(string(out)), "go1.%d", &minor); err != nil {
+		return strings.TrimSpace(string(out)) == "", nil
+	}
+	return minor < 22, nil
+}
+
+// warn writes a warning about unpinned versions once, since BuildAll calls Build for each tool.
+func (m *managerImpl) warn() {
+	m.warnOnce.Do(func() {
+		fmt.Fprintln(m.errW, "gex: warning: versions of tools are not pinned in GOPATH mode")
+	})
+}
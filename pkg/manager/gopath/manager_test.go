@@ -0,0 +1,149 @@
+package gopath_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"log"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/izumin5210/execx"
+
+	"github.com/izumin5210/gex/pkg/manager"
+	"github.com/izumin5210/gex/pkg/manager/gopath"
+)
+
+func TestManager(t *testing.T) {
+	pkgs := []string{"github.com/golang/mock/mockgen", "golang.org/x/lint/golint"}
+
+	cases := []struct {
+		test      string
+		goVersion string
+		inGOPATH  []string
+		wantCmds  [][]string
+		wantErr   string
+	}{
+		{
+			test:      "download missing packages",
+			goVersion: "go1.13.4",
+			inGOPATH:  []string{"golang.org/x/lint/golint"},
+			wantCmds: [][]string{
+				{"go", "list", "-e", "-f", "{{if .Error}}{{.ImportPath}}{{end}}", "github.com/golang/mock/mockgen", "golang.org/x/lint/golint"},
+				{"go", "env", "GOVERSION"},
+				{"go", "get", "-d", "github.com/golang/mock/mockgen"},
+			},
+		},
+		{
+			test:      "before GOVERSION",
+			goVersion: "",
+			wantCmds: [][]string{
+				{"go", "list", "-e", "-f", "{{if .Error}}{{.ImportPath}}{{end}}", "github.com/golang/mock/mockgen", "golang.org/x/lint/golint"},
+				{"go", "env", "GOVERSION"},
+				{"go", "get", "-d", "github.com/golang/mock/mockgen", "golang.org/x/lint/golint"},
+			},
+		},
+		{
+			test:      "all packages are in GOPATH",
+			goVersion: "go1.22.0",
+			inGOPATH:  pkgs,
+			wantCmds: [][]string{
+				{"go", "list", "-e", "-f", "{{if .Error}}{{.ImportPath}}{{end}}", "github.com/golang/mock/mockgen", "golang.org/x/lint/golint"},
+			},
+		},
+		{
+			test:      "go get is disabled",
+			goVersion: "go1.22.0",
+			inGOPATH:  []string{"golang.org/x/lint/golint"},
+			wantCmds: [][]string{
+				{"go", "list", "-e", "-f", "{{if .Error}}{{.ImportPath}}{{end}}", "github.com/golang/mock/mockgen", "golang.org/x/lint/golint"},
+				{"go", "env", "GOVERSION"},
+			},
+			wantErr: "github.com/golang/mock/mockgen not found in GOPATH, and `go get` does not download packages in GOPATH mode since Go 1.22",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			var (
+				cmds [][]string
+				errW bytes.Buffer
+			)
+			fakeExec := execx.New(execx.WithFakeProcess(func(_ context.Context, cmd *exec.Cmd) error {
+				cmds = append(cmds, cmd.Args)
+				if diff := cmp.Diff([]string{"GO111MODULE=off"}, cmd.Env); diff != "" {
+					t.Errorf("%v is executed with unexpected env: (-want +got)\n%s", cmd.Args, diff)
+				}
+				switch {
+				case cmd.Args[1] == "list":
+					for _, pkg := range cmd.Args[5:] {
+						if !contains(tc.inGOPATH, pkg) {
+							io.WriteString(cmd.Stdout, pkg+"\n")
+						}
+					}
+				case cmd.Args[1] == "env":
+					io.WriteString(cmd.Stdout, tc.goVersion+"\n")
+				}
+				return nil
+			}))
+			executor := manager.NewExecutorWithEnv(fakeExec, ioutil.Discard, ioutil.Discard, nil, "/go/src/awesomeapp", []string{"GO111MODULE=on"}, log.New(ioutil.Discard, "", 0))
+			m := gopath.NewManager(executor, &errW)
+
+			err := m.Add(context.Background(), []string{"github.com/golang/mock/mockgen@v1.3.1", "golang.org/x/lint/golint"}, false)
+
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("Add() returned %v, want an error containing %q", err, tc.wantErr)
+				}
+			} else if err != nil {
+				t.Errorf("Add() returned an error: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantCmds, cmds); diff != "" {
+				t.Errorf("executed commands differ: (-want +got)\n%s", diff)
+			}
+
+			wantWarn := "gex: warning: versions of tools are not pinned in GOPATH mode\n" +
+				"gex: warning: the version of github.com/golang/mock/mockgen is ignored in GOPATH mode\n"
+			if got := errW.String(); got != wantWarn {
+				t.Errorf("warnings are\n%s\nwant\n%s", got, wantWarn)
+			}
+		})
+	}
+}
+
+func TestManager_Build(t *testing.T) {
+	var cmds [][]string
+	fakeExec := execx.New(execx.WithFakeProcess(func(_ context.Context, cmd *exec.Cmd) error {
+		cmds = append(cmds, cmd.Args)
+		return nil
+	}))
+	executor := manager.NewExecutor(fakeExec, ioutil.Discard, ioutil.Discard, nil, "/go/src/awesomeapp", log.New(ioutil.Discard, "", 0))
+	m := gopath.NewManager(executor, ioutil.Discard)
+
+	for _, pkg := range []string{"github.com/golang/mock/mockgen", "golang.org/x/lint/golint"} {
+		err := m.Build(context.Background(), "/go/src/awesomeapp/bin/"+pkg, pkg, manager.BuildOptions{})
+		if err != nil {
+			t.Fatalf("Build() returned an error: %v", err)
+		}
+	}
+
+	wantCmds := [][]string{
+		{"go", "build", "-o", "/go/src/awesomeapp/bin/github.com/golang/mock/mockgen", "github.com/golang/mock/mockgen"},
+		{"go", "build", "-o", "/go/src/awesomeapp/bin/golang.org/x/lint/golint", "golang.org/x/lint/golint"},
+	}
+	if diff := cmp.Diff(wantCmds, cmds); diff != "" {
+		t.Errorf("executed commands differ: (-want +got)\n%s", diff)
+	}
+}
+
+func contains(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -1,9 +1,46 @@
 package manager
 
-import "context"
+import (
+	"context"
+	"io"
+)
 
 type Interface interface {
 	Add(ctx context.Context, pkgs []string, verbose bool) error
-	Build(ctx context.Context, binPath, pkg string, verbose bool) error
+	Build(ctx context.Context, binPath, pkg string, opts BuildOptions) error
 	Sync(ctx context.Context, verbose bool) error
 }
+
+// BuildOptions contains options for Interface.Build.
+type BuildOptions struct {
+	Verbose bool
+	// Flags contains additional flags for `go build` (e.g. "-x").
+	Flags []string
+	// Stderr receives stderr of `go build` instead of the error writer of the executor if non-nil.
+	Stderr io.Writer
+}
+
+// Downloader is an optional interface for managers that can fetch sources in advance.
+type Downloader interface {
+	// Download fetches sources required to build given packages so that they can be built without network access.
+	Download(ctx context.Context, pkgs []string, verbose bool) error
+}
+
+// VersionReader is an optional interface for managers that pin versions of tools.
+type VersionReader interface {
+	// Versions returns versions of modules or projects that provide given packages.
+	Versions(ctx context.Context, pkgs []string) (map[string]string, error)
+}
+
+// CommandFinder is an optional interface for managers that can search the dependencies of the project for commands.
+type CommandFinder interface {
+	// FindCommands returns packages of main commands that have the given name.
+	FindCommands(ctx context.Context, name string) ([]string, error)
+}
+
+// PinnedVersionReader is an optional interface for managers that can read versions pinned in files of the project,
+// such as go.mod and Gopkg.lock, without the module cache and network access.
+type PinnedVersionReader interface {
+	// PinnedVersions returns versions of modules or projects that provide given packages.
+	PinnedVersions(ctx context.Context, pkgs []string) (map[string]string, error)
+}
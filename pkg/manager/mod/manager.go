@@ -1,22 +1,64 @@
 package mod
 
 import (
+	"bytes"
 	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
 
+	"github.com/izumin5210/execx"
 	"github.com/pkg/errors"
+	"github.com/spf13/afero"
 
 	"github.com/izumin5210/gex/pkg/manager"
 )
 
+func init() {
+	manager.Register(manager.TypeModules, &manager.Backend{
+		Detect: Detect,
+		New: func(opts *manager.Options) manager.Interface {
+			return NewManager(opts.Executor, opts.FS, opts.RootDir)
+		},
+	})
+}
+
+// Detect returns a root directory of the module that contains workDir.
+func Detect(workDir string, fs afero.Fs, exec *execx.Executor) (string, bool) {
+	out, err := exec.Command("go", "env", "GOMOD").CombinedOutput()
+	// GOMOD is os.DevNull when outside of modules with GO111MODULE=on (or unset since Go 1.16)
+	if gomod := string(bytes.TrimRight(out, "\n")); err == nil && gomod != "" && gomod != os.DevNull {
+		return filepath.Dir(gomod), true
+	}
+
+	dir, err := manager.FindRoot(workDir, fs, "go.mod")
+	if err == nil {
+		return dir, true
+	}
+
+	if os.Getenv("GO111MODULE") == "on" {
+		return workDir, true
+	}
+
+	return "", false
+}
+
 // NewManager creates a manager.Interface instance to build tools vendored with Modules.
-func NewManager(executor manager.Executor) manager.Interface {
+// If the module in rootDir has a vendor directory, tools are built from it.
+func NewManager(executor manager.Executor, fs afero.Fs, rootDir string) manager.Interface {
 	return &managerImpl{
 		executor: executor,
+		fs:       fs,
+		rootDir:  rootDir,
 	}
 }
 
 type managerImpl struct {
 	executor manager.Executor
+	fs       afero.Fs
+	rootDir  string
 }
 
 func (m *managerImpl) Add(ctx context.Context, pkgs []string, verbose bool) error {
@@ -28,13 +70,21 @@ func (m *managerImpl) Add(ctx context.Context, pkgs []string, verbose bool) erro
 	return errors.WithStack(m.executor.Exec(ctx, "go", args...))
 }
 
-func (m *managerImpl) Build(ctx context.Context, binPath, pkg string, verbose bool) error {
+func (m *managerImpl) Build(ctx context.Context, binPath, pkg string, opts manager.BuildOptions) error {
 	args := []string{"build", "-o", binPath}
-	if verbose {
+	if m.vendored() {
+		err := m.checkVendored(pkg)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		args = append(args, "-mod=vendor")
+	}
+	if opts.Verbose {
 		args = append(args, "-v")
 	}
+	args = append(args, opts.Flags...)
 	args = append(args, pkg)
-	return errors.WithStack(m.executor.Exec(ctx, "go", args...))
+	return errors.WithStack(manager.ExecutorWithStderr(m.executor, opts.Stderr).Exec(ctx, "go", args...))
 }
 
 func (m *managerImpl) Sync(ctx context.Context, verbose bool) error {
@@ -42,5 +92,194 @@ func (m *managerImpl) Sync(ctx context.Context, verbose bool) error {
 	if verbose {
 		args = append(args, "-v")
 	}
+	err := m.executor.Exec(ctx, "go", args...)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if !m.vendored() {
+		return nil
+	}
+
+	args = []string{"mod", "vendor"}
+	if verbose {
+		args = append(args, "-v")
+	}
 	return errors.WithStack(m.executor.Exec(ctx, "go", args...))
 }
+
+func (m *managerImpl) Download(ctx context.Context, pkgs []string, verbose bool) error {
+	if m.vendored() {
+		// all sources are in the vendor directory
+		return nil
+	}
+
+	const format = "{{with .Module}}{{if not .Main}}{{.Path}}{{end}}{{end}}"
+	args := append([]string{"list", "-deps", "-f", format}, pkgs...)
+	out, err := m.executor.Output(ctx, "go", args...)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	args = []string{"mod", "download"}
+	if verbose {
+		args = append(args, "-x")
+	}
+	seen := make(map[string]struct{})
+	for _, mod := range strings.Fields(string(out)) {
+		if _, ok := seen[mod]; ok {
+			continue
+		}
+		seen[mod] = struct{}{}
+		args = append(args, mod)
+	}
+	if len(seen) == 0 {
+		return nil
+	}
+	return errors.WithStack(m.executor.Exec(ctx, "go", args...))
+}
+
+func (m *managerImpl) Versions(ctx context.Context, pkgs []string) (map[string]string, error) {
+	const format = "{{.ImportPath}} {{with .Module}}{{if .Replace}}{{.Replace.Version}}{{else}}{{.Version}}{{end}}{{end}}"
+	args := []string{"list", "-f", format}
+	if m.vendored() {
+		args = append(args, "-mod=vendor")
+	}
+	args = append(args, pkgs...)
+	out, err := m.executor.Output(ctx, "go", args...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	versions := make(map[string]string, len(pkgs))
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		switch len(fields) {
+		case 1:
+			versions[fields[0]] = ""
+		case 2:
+			versions[fields[0]] = fields[1]
+		}
+	}
+
+	return versions, nil
+}
+
+// PinnedVersions reads versions of modules that provide given packages from go.mod.
+// Versions of packages in the main module are empty, same as Versions.
+func (m *managerImpl) PinnedVersions(ctx context.Context, pkgs []string) (map[string]string, error) {
+	f, err := ReadModFile(m.fs, m.rootDir)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	versions := make(map[string]string, len(pkgs))
+	for _, pkg := range pkgs {
+		versions[pkg], _ = f.Version(pkg)
+	}
+	return versions, nil
+}
+
+// FindCommands searches modules in the build list for main packages that have the given name.
+// Only directories that are commonly used for commands (the module root, <name> and cmd/<name>) are searched.
+// If the module is vendored, modules in vendor/modules.txt are searched instead,
+// since `go list -m` does not work with -mod=vendor.
+func (m *managerImpl) FindCommands(ctx context.Context, name string) ([]string, error) {
+	mods, err := m.listModules(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var candidates []string
+	for modPath, modDir := range mods {
+		for _, rel := range []string{"", name, "cmd/" + name} {
+			pkg := path.Join(modPath, rel)
+			if path.Base(pkg) != name {
+				continue
+			}
+			if ok, err := afero.DirExists(m.fs, filepath.Join(modDir, filepath.FromSlash(rel))); err == nil && ok {
+				candidates = append(candidates, pkg)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	sort.Strings(candidates)
+
+	args := []string{"list", "-e", "-f", "{{if eq .Name \"main\"}}{{.ImportPath}}{{end}}"}
+	if m.vendored() {
+		args = append(args, "-mod=vendor")
+	}
+	out, err := m.executor.Output(ctx, "go", append(args, candidates...)...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var pkgs []string
+	for _, pkg := range strings.Split(string(out), "\n") {
+		if pkg != "" {
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	return pkgs, nil
+}
+
+// listModules returns directories of modules in the build list, keyed by their paths.
+// Modules that have not been downloaded are omitted.
+func (m *managerImpl) listModules(ctx context.Context) (map[string]string, error) {
+	mods := make(map[string]string)
+
+	if m.vendored() {
+		data, err := afero.ReadFile(m.fs, filepath.Join(m.rootDir, "vendor", "modules.txt"))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			// e.g. # github.com/golang/mock v1.4.0
+			fields := strings.Fields(line)
+			if len(fields) < 2 || fields[0] != "#" {
+				continue
+			}
+			mods[fields[1]] = filepath.Join(m.rootDir, "vendor", filepath.FromSlash(fields[1]))
+		}
+		return mods, nil
+	}
+
+	out, err := m.executor.Output(ctx, "go", "list", "-m", "-f", "{{.Path}} {{.Dir}}", "all")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.SplitN(line, " ", 2)
+		// modules that have not been downloaded do not have directories
+		if len(fields) != 2 || fields[1] == "" {
+			continue
+		}
+		mods[fields[0]] = fields[1]
+	}
+	return mods, nil
+}
+
+func (m *managerImpl) vendored() bool {
+	return manager.ModVendored(m.fs, m.rootDir)
+}
+
+// checkVendored returns an error if the package is not copied into the vendor directory.
+// Packages in the main module are never vendored.
+func (m *managerImpl) checkVendored(pkg string) error {
+	f, err := ReadModFile(m.fs, m.rootDir)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if f.InMainModule(pkg) {
+		return nil
+	}
+	if ok, err := afero.DirExists(m.fs, filepath.Join(m.rootDir, "vendor", filepath.FromSlash(pkg))); err != nil {
+		return errors.WithStack(err)
+	} else if !ok {
+		return errors.Errorf("%s is not vendored, run `go mod vendor`", pkg)
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package mod_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os/exec"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/izumin5210/execx"
+	"github.com/spf13/afero"
+
+	"github.com/izumin5210/gex/pkg/manager"
+	"github.com/izumin5210/gex/pkg/manager/mod"
+)
+
+func TestManager_Build(t *testing.T) {
+	rootDir := "/home/src/awesomeapp"
+	goMod := "module github.com/foo/awesomeapp\n\nrequire github.com/golang/mock v1.4.0\n"
+
+	cases := []struct {
+		test     string
+		vendored bool
+		pkg      string
+		opts     manager.BuildOptions
+		wantCmds [][]string
+		wantErr  string
+	}{
+		{
+			test:     "modules",
+			pkg:      "github.com/golang/mock/mockgen",
+			wantCmds: [][]string{{"go", "build", "-o", rootDir + "/bin/mockgen", "github.com/golang/mock/mockgen"}},
+		},
+		{
+			test:     "options",
+			pkg:      "github.com/golang/mock/mockgen",
+			opts:     manager.BuildOptions{Verbose: true, Flags: []string{"-x"}},
+			wantCmds: [][]string{{"go", "build", "-o", rootDir + "/bin/mockgen", "-v", "-x", "github.com/golang/mock/mockgen"}},
+		},
+		{
+			test:     "vendored",
+			vendored: true,
+			pkg:      "github.com/golang/mock/mockgen",
+			wantCmds: [][]string{{"go", "build", "-o", rootDir + "/bin/mockgen", "-mod=vendor", "github.com/golang/mock/mockgen"}},
+		},
+		{
+			test:     "main module in vendored",
+			vendored: true,
+			pkg:      "github.com/foo/awesomeapp/cmd/gen",
+			wantCmds: [][]string{{"go", "build", "-o", rootDir + "/bin/gen", "-mod=vendor", "github.com/foo/awesomeapp/cmd/gen"}},
+		},
+		{
+			test:     "not vendored",
+			vendored: true,
+			pkg:      "golang.org/x/lint/golint",
+			wantErr:  "golang.org/x/lint/golint is not vendored, run `go mod vendor`",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			afero.WriteFile(fs, rootDir+"/go.mod", []byte(goMod), 0644)
+			if tc.vendored {
+				afero.WriteFile(fs, rootDir+"/vendor/modules.txt", []byte("# github.com/golang/mock v1.4.0\ngithub.com/golang/mock/mockgen\n"), 0644)
+				fs.MkdirAll(rootDir+"/vendor/github.com/golang/mock/mockgen", 0755)
+			}
+
+			var cmds [][]string
+			fakeExec := execx.New(execx.WithFakeProcess(func(_ context.Context, cmd *exec.Cmd) error {
+				cmds = append(cmds, cmd.Args)
+				return nil
+			}))
+			executor := manager.NewExecutor(fakeExec, ioutil.Discard, ioutil.Discard, nil, rootDir, log.New(ioutil.Discard, "", 0))
+			m := mod.NewManager(executor, fs, rootDir)
+
+			err := m.Build(context.Background(), rootDir+"/bin/"+path.Base(tc.pkg), tc.pkg, tc.opts)
+
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("Build() returned %v, want an error containing %q", err, tc.wantErr)
+				}
+			} else if err != nil {
+				t.Errorf("Build() returned an error: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantCmds, cmds); diff != "" {
+				t.Errorf("executed commands differ: (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestManager_Sync(t *testing.T) {
+	rootDir := "/home/src/awesomeapp"
+
+	cases := []struct {
+		test     string
+		vendored bool
+		wantCmds [][]string
+	}{
+		{
+			test:     "modules",
+			wantCmds: [][]string{{"go", "mod", "tidy"}},
+		},
+		{
+			test:     "vendored",
+			vendored: true,
+			wantCmds: [][]string{{"go", "mod", "tidy"}, {"go", "mod", "vendor"}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			if tc.vendored {
+				afero.WriteFile(fs, rootDir+"/vendor/modules.txt", nil, 0644)
+			}
+
+			var cmds [][]string
+			fakeExec := execx.New(execx.WithFakeProcess(func(_ context.Context, cmd *exec.Cmd) error {
+				cmds = append(cmds, cmd.Args)
+				return nil
+			}))
+			executor := manager.NewExecutor(fakeExec, ioutil.Discard, ioutil.Discard, nil, rootDir, log.New(ioutil.Discard, "", 0))
+			m := mod.NewManager(executor, fs, rootDir)
+
+			err := m.Sync(context.Background(), false)
+			if err != nil {
+				t.Fatalf("Sync() returned an error: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantCmds, cmds); diff != "" {
+				t.Errorf("executed commands differ: (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestManager_FindCommands(t *testing.T) {
+	rootDir := "/home/src/awesomeapp"
+	modCache := "/home/go/pkg/mod"
+
+	cases := []struct {
+		test     string
+		files    []string
+		modules  string
+		wantCmds [][]string
+	}{
+		{
+			test:    "modules",
+			files:   []string{modCache + "/github.com/golang/mock@v1.4.0/mockgen"},
+			modules: "awesomeapp " + rootDir + "\ngithub.com/golang/mock " + modCache + "/github.com/golang/mock@v1.4.0\ngolang.org/x/tools\n",
+			wantCmds: [][]string{
+				{"go", "list", "-m", "-f", "{{.Path}} {{.Dir}}", "all"},
+				{"go", "list", "-e", "-f", "{{if eq .Name \"main\"}}{{.ImportPath}}{{end}}", "github.com/golang/mock/mockgen"},
+			},
+		},
+		{
+			test: "vendored",
+			files: []string{
+				rootDir + "/vendor/modules.txt",
+				rootDir + "/vendor/github.com/golang/mock/mockgen",
+			},
+			wantCmds: [][]string{
+				{"go", "list", "-e", "-f", "{{if eq .Name \"main\"}}{{.ImportPath}}{{end}}", "-mod=vendor", "github.com/golang/mock/mockgen"},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			for _, f := range tc.files {
+				var err error
+				if strings.HasSuffix(f, ".txt") {
+					err = afero.WriteFile(fs, f, []byte("# github.com/golang/mock v1.4.0\ngithub.com/golang/mock/mockgen\n"), 0644)
+				} else {
+					err = fs.MkdirAll(f, 0755)
+				}
+				if err != nil {
+					t.Fatalf("failed to create %s: %v", f, err)
+				}
+			}
+
+			var cmds [][]string
+			fakeExec := execx.New(execx.WithFakeProcess(func(_ context.Context, cmd *exec.Cmd) error {
+				cmds = append(cmds, cmd.Args)
+				if cmd.Args[2] == "-m" {
+					fmt.Fprint(cmd.Stdout, tc.modules)
+				} else {
+					fmt.Fprintln(cmd.Stdout, cmd.Args[len(cmd.Args)-1])
+				}
+				return nil
+			}))
+			executor := manager.NewExecutor(fakeExec, ioutil.Discard, ioutil.Discard, nil, rootDir, log.New(ioutil.Discard, "", 0))
+			m := mod.NewManager(executor, fs, rootDir).(manager.CommandFinder)
+
+			pkgs, err := m.FindCommands(context.Background(), "mockgen")
+			if err != nil {
+				t.Fatalf("FindCommands() returned an error: %v", err)
+			}
+
+			if diff := cmp.Diff([]string{"github.com/golang/mock/mockgen"}, pkgs); diff != "" {
+				t.Errorf("FindCommands() returned unexpected packages: (-want +got)\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantCmds, cmds); diff != "" {
+				t.Errorf("executed commands differ: (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
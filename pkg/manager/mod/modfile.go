@@ -0,0 +1,159 @@
+package mod
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+// ModFile represents requirements and replacements declared in go.mod.
+type ModFile struct {
+	// Module is a path of the main module.
+	Module string
+	// Require maps module paths to required versions.
+	Require map[string]string
+	// Replace maps module paths to versions of their replacements. Versions of local directories are empty.
+	Replace map[string]string
+}
+
+// ReadModFile reads go.mod in rootDir.
+func ReadModFile(fs afero.Fs, rootDir string) (*ModFile, error) {
+	modPath := filepath.Join(rootDir, "go.mod")
+	data, err := afero.ReadFile(fs, modPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", modPath)
+	}
+	f, err := ParseModFile(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", modPath)
+	}
+	return f, nil
+}
+
+// ParseModFile parses require and replace directives in contents of go.mod.
+func ParseModFile(data []byte) (*ModFile, error) {
+	f := &ModFile{
+		Require: make(map[string]string),
+		Replace: make(map[string]string),
+	}
+
+	var block string
+	for i, line := range strings.Split(string(data), "\n") {
+		if idx := strings.Index(line, "//"); idx >= 0 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		verb := block
+		switch {
+		case block != "" && fields[0] == ")":
+			block = ""
+			continue
+		case block == "" && len(fields) == 2 && fields[1] == "(":
+			block = fields[0]
+			continue
+		case block == "":
+			verb, fields = fields[0], fields[1:]
+		}
+
+		var err error
+		switch verb {
+		case "module":
+			err = f.parseModule(fields)
+		case "require":
+			err = f.parseRequire(fields)
+		case "replace":
+			err = f.parseReplace(fields)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", i+1)
+		}
+	}
+
+	return f, nil
+}
+
+func (f *ModFile) parseModule(fields []string) error {
+	if len(fields) != 1 {
+		return errors.New("usage: module module/path")
+	}
+	path, err := unquote(fields[0])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	f.Module = path
+	return nil
+}
+
+func (f *ModFile) parseRequire(fields []string) error {
+	if len(fields) != 2 {
+		return errors.New("usage: require module/path v1.2.3")
+	}
+	path, err := unquote(fields[0])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	f.Require[path] = fields[1]
+	return nil
+}
+
+func (f *ModFile) parseReplace(fields []string) error {
+	arrow := -1
+	for i, s := range fields {
+		if s == "=>" {
+			arrow = i
+		}
+	}
+	if arrow < 1 || arrow > 2 || len(fields)-arrow < 2 || len(fields)-arrow > 3 {
+		return errors.New("usage: replace module/path [v1.2.3] => other/module [v1.4.5]")
+	}
+	path, err := unquote(fields[0])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	var version string
+	if len(fields)-arrow == 3 {
+		version = fields[arrow+2]
+	}
+	f.Replace[path] = version
+	return nil
+}
+
+// Version returns a version of the module that provides the package.
+// It returns false if no required modules provide the package, e.g. the package is in the main module.
+func (f *ModFile) Version(pkg string) (string, bool) {
+	var modPath string
+	for p := range f.Require {
+		if (pkg == p || strings.HasPrefix(pkg, p+"/")) && len(p) > len(modPath) {
+			modPath = p
+		}
+	}
+	if modPath == "" {
+		return "", false
+	}
+	if v, ok := f.Replace[modPath]; ok {
+		return v, true
+	}
+	return f.Require[modPath], true
+}
+
+// InMainModule returns true if the package is in the main module.
+func (f *ModFile) InMainModule(pkg string) bool {
+	if _, ok := f.Version(pkg); ok {
+		return false
+	}
+	return f.Module != "" && (pkg == f.Module || strings.HasPrefix(pkg, f.Module+"/"))
+}
+
+func unquote(s string) (string, error) {
+	if strings.HasPrefix(s, `"`) || strings.HasPrefix(s, "`") {
+		return strconv.Unquote(s)
+	}
+	return s, nil
+}
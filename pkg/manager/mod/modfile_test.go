@@ -0,0 +1,84 @@
+package mod_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/izumin5210/gex/pkg/manager/mod"
+)
+
+func TestModFile_Version(t *testing.T) {
+	f, err := mod.ParseModFile([]byte(`module github.com/foo/awesomeapp
+
+go 1.21.0
+
+require github.com/golang/mock v1.3.1 // indirect
+
+require (
+	golang.org/x/tools v0.1.0
+	golang.org/x/tools/gopls v0.6.0
+	"github.com/spf13/pflag" v1.0.5
+)
+
+replace github.com/spf13/pflag => github.com/foo/pflag v1.0.6
+
+replace (
+	golang.org/x/tools v0.1.0 => ../tools
+)
+`))
+	if err != nil {
+		t.Fatalf("ParseModFile() returned an error: %v", err)
+	}
+
+	cases := []struct {
+		pkg    string
+		want   string
+		wantOK bool
+	}{
+		{pkg: "github.com/golang/mock/mockgen", want: "v1.3.1", wantOK: true},
+		{pkg: "golang.org/x/tools/gopls", want: "v0.6.0", wantOK: true},
+		{pkg: "golang.org/x/tools/cmd/stringer", want: "", wantOK: true},
+		{pkg: "github.com/spf13/pflag", want: "v1.0.6", wantOK: true},
+		{pkg: "github.com/golang/mockery", want: "", wantOK: false},
+		{pkg: "github.com/foo/awesomeapp/cmd/app", want: "", wantOK: false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.pkg, func(t *testing.T) {
+			got, ok := f.Version(tc.pkg)
+			if diff := cmp.Diff([]interface{}{tc.want, tc.wantOK}, []interface{}{got, ok}); diff != "" {
+				t.Errorf("Version() returned unexpected values: (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestModFile_InMainModule(t *testing.T) {
+	f, err := mod.ParseModFile([]byte(`module github.com/foo/awesomeapp
+
+require github.com/foo/awesomeapp/tools v0.1.0
+`))
+	if err != nil {
+		t.Fatalf("ParseModFile() returned an error: %v", err)
+	}
+
+	cases := []struct {
+		pkg  string
+		want bool
+	}{
+		{pkg: "github.com/foo/awesomeapp", want: true},
+		{pkg: "github.com/foo/awesomeapp/cmd/app", want: true},
+		{pkg: "github.com/foo/awesomeapp/tools/cmd/gen", want: false},
+		{pkg: "github.com/foo/awesomeapp2/cmd/app", want: false},
+		{pkg: "github.com/golang/mock/mockgen", want: false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.pkg, func(t *testing.T) {
+			if got := f.InMainModule(tc.pkg); got != tc.want {
+				t.Errorf("InMainModule() returned %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package manager
+
+import (
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/izumin5210/execx"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+// Options contains parameters to create a manager.
+type Options struct {
+	Executor   Executor
+	FS         afero.Fs
+	RootDir    string
+	WorkingDir string
+	ErrWriter  io.Writer
+}
+
+// Backend describes a dependencies management tool.
+type Backend struct {
+	// Name is a name of the tool, that is returned from Type.String().
+	Name string
+	// Priority decides an order of detection. Backends with higher priority are detected first.
+	Priority int
+	// Detect returns a root directory of the project if it is managed with the tool.
+	// Backends without Detect are used only when they are specified explicitly.
+	Detect func(workDir string, fs afero.Fs, exec *execx.Executor) (rootDir string, ok bool)
+	// New creates a manager.
+	New func(opts *Options) Interface
+
+	// Vendor is a hint for the manifest writer. If true, tools are built from "./vendor/<package>".
+	Vendor bool
+	// BuildFlags is a hint for the manifest writer that returns additional flags for `go build`.
+	BuildFlags func(fs afero.Fs, rootDir string) []string
+	// LockFiles is a hint for generated build scripts. Tools are rebuilt when the files are changed.
+	// Paths are relative to the root directory, and files that do not exist are ignored.
+	LockFiles []string
+}
+
+var registry = struct {
+	sync.RWMutex
+	backends map[Type]*Backend
+	next     Type
+}{
+	backends: builtinBackends(),
+	next:     TypeGOPATH + 1,
+}
+
+// NewType allocates a new Type for a backend that is not built into gex.
+func NewType() Type {
+	registry.Lock()
+	defer registry.Unlock()
+	t := registry.next
+	registry.next++
+	return t
+}
+
+// Register makes a backend available as the given Type.
+// Hints of built-in Types, such as Name and Vendor, are defined in this package, and they are used if b does not have them.
+// It panics if the Type is already registered.
+func Register(t Type, b *Backend) {
+	registry.Lock()
+	defer registry.Unlock()
+	if t == TypeUnknown {
+		panic("manager: Register with TypeUnknown")
+	}
+	if b == nil || b.New == nil {
+		panic("manager: Register backend is nil")
+	}
+	if old, ok := registry.backends[t]; ok {
+		if old.New != nil {
+			panic("manager: Register called twice for " + old.Name)
+		}
+		b = withHints(b, old)
+	}
+	registry.backends[t] = b
+}
+
+// withHints returns a copy of b that has hints of the built-in backend if b does not have them.
+func withHints(b, builtin *Backend) *Backend {
+	newB := *b
+	if newB.Name == "" {
+		newB.Name = builtin.Name
+	}
+	if newB.Priority == 0 {
+		newB.Priority = builtin.Priority
+	}
+	newB.Vendor = newB.Vendor || builtin.Vendor
+	if newB.BuildFlags == nil {
+		newB.BuildFlags = builtin.BuildFlags
+	}
+	if newB.LockFiles == nil {
+		newB.LockFiles = builtin.LockFiles
+	}
+	return &newB
+}
+
+// Lookup returns a backend registered as the given Type.
+// Backends of built-in Types are returned even if their packages are not imported, but they do not have Detect and New.
+func Lookup(t Type) (*Backend, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	b, ok := registry.backends[t]
+	return b, ok
+}
+
+// New creates a manager of the given Type.
+func New(t Type, opts *Options) (Interface, error) {
+	b, ok := Lookup(t)
+	if !ok || b.New == nil {
+		return nil, errors.Wrapf(ErrUnknownType, "type %d is not registered", int(t))
+	}
+	return b.New(opts), nil
+}
+
+// registeredTypes returns registered types in order of priority.
+func registeredTypes() []Type {
+	registry.RLock()
+	defer registry.RUnlock()
+	types := make([]Type, 0, len(registry.backends))
+	for t := range registry.backends {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		bi, bj := registry.backends[types[i]], registry.backends[types[j]]
+		if bi.Priority != bj.Priority {
+			return bi.Priority > bj.Priority
+		}
+		return types[i] < types[j]
+	})
+	return types
+}
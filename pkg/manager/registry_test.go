@@ -0,0 +1,67 @@
+package manager_test
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/izumin5210/execx"
+	"github.com/spf13/afero"
+
+	"github.com/izumin5210/gex/pkg/manager"
+	_ "github.com/izumin5210/gex/pkg/manager/dep"
+)
+
+type fakeManager struct {
+	manager.Interface
+}
+
+func TestRegister(t *testing.T) {
+	typ := manager.NewType()
+	manager.Register(typ, &manager.Backend{
+		Name:     "fake",
+		Priority: 100,
+		Detect: func(workDir string, fs afero.Fs, _ *execx.Executor) (string, bool) {
+			root, err := manager.FindRoot(workDir, fs, "fake.lock")
+			return root, err == nil
+		},
+		New: func(*manager.Options) manager.Interface { return &fakeManager{} },
+	})
+
+	wd := "/go/src/awesomeapp/foobar"
+	fs := afero.NewMemMapFs()
+	for _, name := range []string{"fake.lock", "Gopkg.toml"} {
+		err := afero.WriteFile(fs, filepath.Join(filepath.Dir(wd), name), []byte(""), 0644)
+		if err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	if got, want := typ.String(), "fake"; got != want {
+		t.Errorf("String() returned %q, want %q", got, want)
+	}
+
+	if got, err := manager.ParseType("fake"); err != nil {
+		t.Errorf("ParseType() returned an error: %v", err)
+	} else if got != typ {
+		t.Errorf("ParseType() returned %v, want %v", got, typ)
+	}
+
+	execer := execx.New(execx.WithFakeProcess(func(context.Context, *exec.Cmd) error { return nil }))
+	gotType, gotRoot := manager.DetectType(wd, fs, execer)
+	if gotType != typ {
+		t.Errorf("Detected type is %v, want %v", gotType, typ)
+	}
+	if want := filepath.Dir(wd); gotRoot != want {
+		t.Errorf("Detected root is %s, want %s", gotRoot, want)
+	}
+
+	m, err := manager.New(typ, &manager.Options{})
+	if err != nil {
+		t.Fatalf("New() returned an error: %v", err)
+	}
+	if _, ok := m.(*fakeManager); !ok {
+		t.Errorf("New() returned %T, want *fakeManager", m)
+	}
+}
@@ -1,8 +1,6 @@
 package manager
 
 import (
-	"bytes"
-	"os"
 	"path/filepath"
 
 	"github.com/izumin5210/execx"
@@ -13,36 +11,51 @@ import (
 // Type represents the dependencies management tool that is used.
 type Type int
 
-// Type values
+// ErrUnknownType is returned when the dependencies management tool is unknown or not registered.
+var ErrUnknownType = errors.New("unknown dependencies management tool")
+
+// Type values of built-in backends.
+// Other backends can allocate their own values with NewType.
 const (
 	TypeUnknown Type = iota
 	TypeModules
 	TypeDep
+	TypeGOPATH
 )
 
-func (t Type) Vendor() bool { return t == TypeDep }
+// Vendor returns true if tools are built from the vendor directory of the project.
+func (t Type) Vendor() bool {
+	b, ok := Lookup(t)
+	return ok && b.Vendor
+}
 
 func (t Type) String() string {
-	switch t {
-	case TypeModules:
-		return "mod"
-	case TypeDep:
-		return "dep"
-	default:
-		return "unknown"
+	if b, ok := Lookup(t); ok {
+		return b.Name
 	}
+	return "unknown"
 }
 
-// DetectType detects a current Mode and sets a root directory.
-func DetectType(workDir string, fs afero.Fs, exec *execx.Executor) (t Type, rootDir string) {
-	root, err := FindRoot(workDir, fs, "Gopkg.toml")
-	if err == nil {
-		return TypeDep, root
+// ParseType returns a Type from its name.
+func ParseType(s string) (Type, error) {
+	for _, t := range registeredTypes() {
+		if t.String() == s {
+			return t, nil
+		}
 	}
+	return TypeUnknown, errors.Wrapf(ErrUnknownType, "failed to parse %q", s)
+}
 
-	dir, ok := lookupMod(workDir, fs, exec)
-	if ok {
-		return TypeModules, dir
+// DetectType detects a current Mode and sets a root directory.
+func DetectType(workDir string, fs afero.Fs, exec *execx.Executor) (t Type, rootDir string) {
+	for _, t := range registeredTypes() {
+		b, _ := Lookup(t)
+		if b.Detect == nil {
+			continue
+		}
+		if root, ok := b.Detect(workDir, fs, exec); ok {
+			return t, root
+		}
 	}
 
 	return TypeUnknown, ""
@@ -64,21 +77,3 @@ func FindRoot(from string, fs afero.Fs, manifest string) (string, error) {
 		from = parent
 	}
 }
-
-func lookupMod(workDir string, fs afero.Fs, exec *execx.Executor) (string, bool) {
-	out, err := exec.Command("go", "env", "GOMOD").CombinedOutput()
-	if err == nil && len(bytes.TrimRight(out, "\n")) > 0 {
-		return filepath.Dir(string(out)), true
-	}
-
-	dir, err := FindRoot(workDir, fs, "go.mod")
-	if err == nil {
-		return dir, true
-	}
-
-	if os.Getenv("GO111MODULE") == "on" {
-		return workDir, true
-	}
-
-	return "", false
-}
@@ -13,6 +13,8 @@ import (
 	"github.com/spf13/afero"
 
 	"github.com/izumin5210/gex/pkg/manager"
+	_ "github.com/izumin5210/gex/pkg/manager/dep"
+	_ "github.com/izumin5210/gex/pkg/manager/mod"
 )
 
 func TestDetectType(t *testing.T) {
@@ -103,6 +105,12 @@ func TestDetectType(t *testing.T) {
 			execer: createExec(t, ""),
 			typ:    manager.TypeUnknown,
 		},
+		{
+			test:   "unknown outside of modules",
+			fs:     createFS(t),
+			execer: createExec(t, os.DevNull),
+			typ:    manager.TypeUnknown,
+		},
 	}
 
 	for _, tc := range cases {
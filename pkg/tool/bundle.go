@@ -0,0 +1,365 @@
+package tool
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+
+	"github.com/izumin5210/gex/pkg/manager"
+)
+
+const (
+	bundleManifestName = "manifest.json"
+	bundleBinDir       = "bin"
+)
+
+// bundleManifest describes tools packaged in a bundle.
+type bundleManifest struct {
+	ManagerType string       `json:"managerType"`
+	GOOS        string       `json:"goos"`
+	GOARCH      string       `json:"goarch"`
+	Tools       []bundleTool `json:"tools"`
+}
+
+type bundleTool struct {
+	Package string `json:"package"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	SHA256  string `json:"sha256"`
+}
+
+// Export builds all tools and writes them into w as a gzipped tarball with a manifest of their versions and checksums.
+// Binaries in the bin directory are not exported, since they may have been built with other versions.
+func (r *repositoryImpl) Export(ctx context.Context, w io.Writer) error {
+	m, err := r.getManifest()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	tools := m.Tools()
+	versions, err := r.pinnedVersions(ctx, tools)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	err = r.FS.MkdirAll(r.BinDir(), 0755)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	dir, err := afero.TempDir(r.FS, r.BinDir(), ".export")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer r.FS.RemoveAll(dir)
+
+	r.notify(ExportStarted{Tools: tools})
+	err = r.buildInto(ctx, tools, dir)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	bm := &bundleManifest{
+		ManagerType: r.managerType.String(),
+		GOOS:        runtime.GOOS,
+		GOARCH:      runtime.GOARCH,
+		Tools:       make([]bundleTool, 0, len(tools)),
+	}
+	binPaths := make(map[string]string, len(tools))
+	for _, t := range tools {
+		binPath := filepath.Join(dir, t.Name())
+		sum, err := r.checksum(binPath)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		bm.Tools = append(bm.Tools, bundleTool{
+			Package: string(t),
+			Name:    t.Name(),
+			Version: versions[string(t)],
+			SHA256:  sum,
+		})
+		binPaths[t.Name()] = binPath
+	}
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	data, err := json.MarshalIndent(bm, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = tw.WriteHeader(&tar.Header{Name: bundleManifestName, Mode: 0644, Size: int64(len(data))})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	_, err = tw.Write(data)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	for _, bt := range bm.Tools {
+		err = r.writeBundleEntry(tw, bt, binPaths[bt.Name])
+		if err != nil {
+			return errors.Wrapf(err, "failed to export %s", bt.Name)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(gw.Close())
+}
+
+// Import verifies tools packaged in the bundle read from rd against the current manifest and installs them into the bin directory.
+func (r *repositoryImpl) Import(ctx context.Context, rd io.Reader) error {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer unlock()
+
+	m, err := r.getManifest()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	tools := m.Tools()
+	versions, err := r.pinnedVersions(ctx, tools)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	gr, err := gzip.NewReader(rd)
+	if err != nil {
+		return errors.Wrap(err, "failed to read the bundle")
+	}
+	defer gr.Close()
+	tr := tar.NewReader(gr)
+
+	hdr, err := tr.Next()
+	if err != nil {
+		return errors.Wrap(err, "failed to read the bundle")
+	}
+	if hdr.Name != bundleManifestName {
+		return errors.Errorf("the bundle should start with %s, got %s", bundleManifestName, hdr.Name)
+	}
+	var bm bundleManifest
+	err = json.NewDecoder(tr).Decode(&bm)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse %s", bundleManifestName)
+	}
+	if bm.GOOS != runtime.GOOS || bm.GOARCH != runtime.GOARCH {
+		return errors.Errorf("the bundle was built for %s/%s, but current platform is %s/%s", bm.GOOS, bm.GOARCH, runtime.GOOS, runtime.GOARCH)
+	}
+
+	bundled := make(map[string]bundleTool, len(bm.Tools))
+	for _, bt := range bm.Tools {
+		bundled[bt.Package] = bt
+	}
+
+	wants := make(map[string]bundleTool, len(tools))
+	for _, t := range tools {
+		bt, ok := bundled[string(t)]
+		if !ok {
+			return errors.Errorf("%s is not contained in the bundle", t)
+		}
+		if got, want := bt.Version, versions[string(t)]; got != want {
+			return errors.Errorf("%s in the bundle is %q, but the manifest requires %q", t, got, want)
+		}
+		wants[bt.Name] = bt
+	}
+
+	// binaries are staged into temporary files, and installed only if all of them are verified
+	staged := make(map[string]string, len(wants))
+	defer func() {
+		for _, tmpPath := range staged {
+			r.FS.Remove(tmpPath)
+		}
+	}()
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to read the bundle")
+		}
+
+		bt, ok := wants[path.Base(hdr.Name)]
+		if !ok || path.Dir(hdr.Name) != bundleBinDir {
+			continue
+		}
+
+		binPath, err := r.binPath(Tool(bt.Package))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		tmpPath, err := r.stageBundleEntry(tr, bt, binPath)
+		if err != nil {
+			return errors.Wrapf(err, "failed to import %s", bt.Name)
+		}
+		staged[binPath] = tmpPath
+		delete(wants, bt.Name)
+	}
+
+	if len(wants) > 0 {
+		missing := make([]string, 0, len(wants))
+		for _, bt := range wants {
+			missing = append(missing, bt.Package)
+		}
+		sort.Strings(missing)
+		return errors.Errorf("binaries of %s are missing in the bundle", strings.Join(missing, ", "))
+	}
+
+	for _, t := range tools {
+		binPath, err := r.binPath(t)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		err = r.FS.Rename(staged[binPath], binPath)
+		if err != nil {
+			return errors.Wrapf(err, "failed to import %s", t.Name())
+		}
+		delete(staged, binPath)
+		r.notify(ToolImported{Tool: t, BinPath: binPath})
+	}
+
+	return nil
+}
+
+// buildInto builds the tools into the directory even if they have been built in the bin directory.
+func (r *repositoryImpl) buildInto(ctx context.Context, tools []Tool, dir string) error {
+	var (
+		wg   sync.WaitGroup
+		errs BuildErrors
+	)
+
+	for _, t := range tools {
+		t := t
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			binPath := filepath.Join(dir, t.Name())
+			r.notify(BuildStarted{Tool: t, BinPath: binPath})
+			start := time.Now()
+			err := r.buildTo(ctx, binPath, t)
+			r.notify(BuildFinished{Tool: t, BinPath: binPath, Duration: time.Since(start), Err: err})
+			if err != nil {
+				errs.Append(t, errors.WithStack(err))
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if !errs.Empty() {
+		return &errs
+	}
+	return nil
+}
+
+// pinnedVersions returns versions of the tools.
+// They are read from files of the project if the manager supports it, so that bundles can be imported without network access.
+// Versions are empty if the manager does not pin versions.
+func (r *repositoryImpl) pinnedVersions(ctx context.Context, tools []Tool) (map[string]string, error) {
+	var (
+		versions map[string]string
+		err      error
+	)
+	switch m := r.manager.(type) {
+	case manager.PinnedVersionReader:
+		versions, err = m.PinnedVersions(ctx, toolPackages(tools))
+	case manager.VersionReader:
+		versions, err = m.Versions(ctx, toolPackages(tools))
+	}
+	return versions, errors.Wrap(err, "failed to get versions of tools")
+}
+
+func (r *repositoryImpl) writeBundleEntry(tw *tar.Writer, bt bundleTool, binPath string) error {
+	f, err := r.FS.Open(binPath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer f.Close()
+
+	st, err := f.Stat()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	err = tw.WriteHeader(&tar.Header{Name: path.Join(bundleBinDir, bt.Name), Mode: 0755, Size: st.Size()})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	_, err = io.Copy(tw, f)
+	return errors.WithStack(err)
+}
+
+// stageBundleEntry writes the binary read from rd into a temporary file next to binPath, and returns a path of the file.
+func (r *repositoryImpl) stageBundleEntry(rd io.Reader, bt bundleTool, binPath string) (string, error) {
+	err := r.FS.MkdirAll(filepath.Dir(binPath), 0755)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	f, err := afero.TempFile(r.FS, filepath.Dir(binPath), "."+bt.Name)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(f, h), rd)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		if got, want := hex.EncodeToString(h.Sum(nil)), bt.SHA256; got != want {
+			err = errors.Errorf("checksum mismatch: got %s, want %s", got, want)
+		}
+	}
+	if err == nil {
+		err = r.FS.Chmod(f.Name(), 0755)
+	}
+	if err != nil {
+		r.FS.Remove(f.Name())
+		return "", errors.WithStack(err)
+	}
+	return f.Name(), nil
+}
+
+func (r *repositoryImpl) checksum(path string) (string, error) {
+	f, err := r.FS.Open(path)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func toolPackages(tools []Tool) []string {
+	pkgs := make([]string, len(tools))
+	for i, t := range tools {
+		pkgs[i] = string(t)
+	}
+	return pkgs
+}
@@ -0,0 +1,199 @@
+package tool_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+
+	"github.com/izumin5210/gex/pkg/manager"
+	"github.com/izumin5210/gex/pkg/tool"
+)
+
+func TestRepository_ExportImport(t *testing.T) {
+	tools := []tool.Tool{
+		"github.com/golang/mock/mockgen",
+		"golang.org/x/lint/golint",
+	}
+	versions := map[string]string{
+		"github.com/golang/mock/mockgen": "v1.3.1",
+		"golang.org/x/lint/golint":       "v0.0.0-20190930215403-16217165b5de",
+	}
+
+	newRepo := func(t *testing.T, versions map[string]string, obs tool.Observer) (tool.Repository, *tool.Config) {
+		t.Helper()
+		fs := afero.NewMemMapFs()
+		cfg := &tool.Config{
+			FS:           fs,
+			RootDir:      "/home/src/awesomeapp",
+			ManifestName: "tools.go",
+			BinDirName:   "bin",
+			Log:          log.New(ioutil.Discard, "", 0),
+			Observer:     obs,
+		}
+		err := tool.NewWriter(fs).Write(cfg.ManifestPath(), tool.NewManifest(tools, manager.TypeModules))
+		if err != nil {
+			t.Fatalf("failed to write the manifest: %v", err)
+		}
+		return tool.NewRepository(nil, &fakeManager{fs: fs, versions: versions}, manager.TypeModules, cfg), cfg
+	}
+
+	srcObs := new(recordingObserver)
+	src, srcCfg := newRepo(t, versions, srcObs)
+	// binaries in the bin directory may have been built with other versions
+	err := afero.WriteFile(srcCfg.FS, srcCfg.BinPath("mockgen"), []byte("stale"), 0755)
+	if err != nil {
+		t.Fatalf("failed to write a binary: %v", err)
+	}
+	var bundle bytes.Buffer
+	err = src.Export(context.Background(), &bundle)
+	if err != nil {
+		t.Fatalf("Export() returned an error: %v", err)
+	}
+	if diff := cmp.Diff([]tool.Event{tool.ExportStarted{Tools: tools}}, srcObs.events[:1]); diff != "" {
+		t.Errorf("received events differ: (-want +got)\n%s", diff)
+	}
+
+	cases := []struct {
+		test     string
+		versions map[string]string
+		rewrite  func(name string, data []byte) []byte
+		wantErr  string
+	}{
+		{
+			test:     "success",
+			versions: versions,
+		},
+		{
+			test: "version mismatch",
+			versions: map[string]string{
+				"github.com/golang/mock/mockgen": "v1.4.0",
+				"golang.org/x/lint/golint":       "v0.0.0-20190930215403-16217165b5de",
+			},
+			wantErr: `github.com/golang/mock/mockgen in the bundle is "v1.3.1", but the manifest requires "v1.4.0"`,
+		},
+		{
+			test:     "checksum mismatch",
+			versions: versions,
+			rewrite: func(name string, data []byte) []byte {
+				if name == "bin/golint" {
+					return []byte("tampered")
+				}
+				return data
+			},
+			wantErr: "checksum mismatch",
+		},
+		{
+			test:     "different platform",
+			versions: versions,
+			rewrite: func(name string, data []byte) []byte {
+				if name != "manifest.json" {
+					return data
+				}
+				var m map[string]interface{}
+				if err := json.Unmarshal(data, &m); err != nil {
+					t.Fatalf("failed to parse the manifest: %v", err)
+				}
+				m["goos"], m["goarch"] = "plan9", "386"
+				data, err := json.Marshal(m)
+				if err != nil {
+					t.Fatalf("failed to encode the manifest: %v", err)
+				}
+				return data
+			},
+			wantErr: "the bundle was built for plan9/386, but current platform is " + runtime.GOOS + "/" + runtime.GOARCH,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			data := bundle.Bytes()
+			if tc.rewrite != nil {
+				data = rewriteBundle(t, data, tc.rewrite)
+			}
+
+			obs := new(recordingObserver)
+			dst, cfg := newRepo(t, tc.versions, obs)
+			err := dst.Import(context.Background(), bytes.NewReader(data))
+
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("Import() returned %v, want an error containing %q", err, tc.wantErr)
+				}
+				// nothing is installed if any of the binaries is invalid
+				infos, err := afero.ReadDir(cfg.FS, cfg.BinDir())
+				if err == nil && len(infos) > 0 {
+					t.Errorf("%d file(s) are left in the bin directory", len(infos))
+				}
+				if len(obs.events) > 0 {
+					t.Errorf("Import() sent events: %v", obs.events)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Import() returned an error: %v", err)
+			}
+			for _, tl := range tools {
+				got, err := afero.ReadFile(cfg.FS, cfg.BinPath(tl.Name()))
+				if err != nil {
+					t.Fatalf("%s is not installed: %v", tl.Name(), err)
+				}
+				if want := string(tl); string(got) != want {
+					t.Errorf("%s is %q, want %q", tl.Name(), got, want)
+				}
+			}
+			wantEvents := []tool.Event{
+				tool.ToolImported{Tool: tools[0], BinPath: cfg.BinPath("mockgen")},
+				tool.ToolImported{Tool: tools[1], BinPath: cfg.BinPath("golint")},
+			}
+			if diff := cmp.Diff(wantEvents, obs.events); diff != "" {
+				t.Errorf("received events differ: (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+// rewriteBundle returns a copy of the bundle whose entries are rewritten with f.
+func rewriteBundle(t *testing.T, data []byte, f func(name string, data []byte) []byte) []byte {
+	t.Helper()
+
+	gr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to read the bundle: %v", err)
+	}
+	tr := tar.NewReader(gr)
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+		body, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", hdr.Name, err)
+		}
+		body = f(hdr.Name, body)
+		hdr.Size = int64(len(body))
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("failed to write %s: %v", hdr.Name, err)
+		}
+		if _, err := tw.Write(body); err != nil {
+			t.Fatalf("failed to write %s: %v", hdr.Name, err)
+		}
+	}
+	tw.Close()
+	gw.Close()
+	return buf.Bytes()
+}
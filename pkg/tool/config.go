@@ -15,8 +15,21 @@ type Config struct {
 	RootDir      string
 	ManifestName string
 	BinDirName   string
-	Verbose      bool
-	Log          *log.Logger
+	ToolEnv      map[string][]string
+	// Locker serializes operations that modify the manifest and binaries. Operations are not locked if nil.
+	Locker  Locker
+	Verbose bool
+	Log     *log.Logger
+	// Observer receives progress events. Events are written into Log if nil.
+	Observer Observer
+	// BuildFlags contains additional flags for `go build` to build tools.
+	BuildFlags []string
+	// CaptureBuildOutput makes stderr of `go build` sent to Observer as BuildOutput events
+	// instead of written into the error writer.
+	CaptureBuildOutput bool
+	// GexPath is an absolute path of the gex executable that shims execute.
+	// Shims execute gex with `go run` if empty.
+	GexPath string
 }
 
 // RequireManifest returns an error if the manifest file does not exist.
@@ -24,7 +37,7 @@ func (c *Config) RequireManifest() error {
 	if ok, err := afero.Exists(c.FS, c.ManifestPath()); err != nil {
 		return errors.WithStack(err)
 	} else if !ok {
-		return errors.Errorf("could not find %s", c.ManifestPath())
+		return errors.WithStack(&ManifestNotFoundError{Path: c.ManifestPath()})
 	}
 	return nil
 }
@@ -41,6 +54,11 @@ func (c *Config) BinPath(bin string) string {
 	return filepath.Join(c.BinDir(), bin)
 }
 
+// ShimmedBinPath returns a path of the binary that is executed by a shim in BinDir.
+func (c *Config) ShimmedBinPath(bin string) string {
+	return filepath.Join(c.BinDir(), ".gex", bin)
+}
+
 func (c *Config) baseDir() (dir string) {
 	dir = c.RootDir
 	if dir == "" {
@@ -0,0 +1,330 @@
+package tool
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// editManifest updates import specs and `//go:generate` directives of the existing manifest file in place.
+// Comments, directives and declarations that are not managed by gex are kept as they are.
+func editManifest(src []byte, m *Manifest, cmds []*buildCommand, legacyBuildTag bool) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse the manifest file")
+	}
+
+	e := &editor{src: src, fset: fset}
+
+	if !legacyBuildTag {
+		e.removeLegacyBuildTag(f)
+	}
+
+	// import specs
+	existing := make(map[Tool]bool)
+	for _, s := range f.Imports {
+		pkg, err := strconv.Unquote(s.Path.Value)
+		if err != nil {
+			continue
+		}
+		t := Tool(pkg)
+		existing[t] = true
+		if current, ok := m.FindTool(t.Name()); !ok || current != t {
+			e.removeLines(importSpecStart(s), importSpecEnd(s))
+			continue
+		}
+		e.updateGroupAnnotation(s, m.Groups(t))
+	}
+
+	managed := make(map[Tool]bool, len(existing))
+	var added []string
+	for t := range existing {
+		managed[t] = true
+	}
+	for _, t := range m.Tools() {
+		if !existing[t] {
+			added = append(added, "\t"+importSpecLine(t, m.Groups(t))+"\n")
+		}
+		managed[t] = true
+	}
+	if len(added) > 0 {
+		e.insertImports(f, m, strings.Join(added, ""))
+	}
+
+	// go:generate directives
+	var directives []string
+	for _, c := range cmds {
+		for _, d := range c.Directives() {
+			directives = append(directives, d+"\n")
+		}
+	}
+	var inserted bool
+	for _, cg := range f.Comments {
+		for _, c := range cg.List {
+			if !isManagedDirective(c.Text, managed) {
+				continue
+			}
+			start, end := e.lineStart(c.Pos()), e.lineEnd(c.End())
+			if !inserted {
+				e.replace(start, end, strings.Join(directives, ""))
+				inserted = true
+			} else {
+				e.replace(start, end, "")
+			}
+		}
+	}
+	if !inserted && len(directives) > 0 {
+		e.appendDirectives(f, strings.Join(directives, ""))
+	}
+
+	out, err := format.Source(e.apply())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to format the manifest file")
+	}
+	return out, nil
+}
+
+func importSpecLine(t Tool, groups []string) string {
+	line := "_ " + strconv.Quote(string(t))
+	if len(groups) > 0 {
+		line += " // " + groupAnnotation + strings.Join(groups, ",")
+	}
+	return line
+}
+
+func importSpecStart(s *ast.ImportSpec) token.Pos {
+	if s.Doc != nil {
+		return s.Doc.Pos()
+	}
+	return s.Pos()
+}
+
+func importSpecEnd(s *ast.ImportSpec) token.Pos {
+	if s.Comment != nil {
+		return s.Comment.End()
+	}
+	return s.End()
+}
+
+var (
+	// e.g. //go:generate go build -mod=vendor -v -o=./bin/mockgen ./vendor/github.com/golang/mock/mockgen
+	buildDirectivePattern = regexp.MustCompile(`^//go:generate (?:go build|gex:\S+) (?:\S+ )*-o=\./bin/\S+ (?:\./vendor/)?(\S+)$`)
+	// e.g. //go:generate -command gex:lint:golangci-lint go build
+	aliasDirectivePattern = regexp.MustCompile(`^//go:generate -command gex:\S+ go build$`)
+)
+
+// isManagedDirective returns true if the comment is a directive that gex generated for tools.
+// Directives building other packages are written by users, so they are not managed.
+func isManagedDirective(text string, tools map[Tool]bool) bool {
+	text = strings.TrimSpace(text)
+	if aliasDirectivePattern.MatchString(text) {
+		return true
+	}
+	if m := buildDirectivePattern.FindStringSubmatch(text); m != nil {
+		return tools[Tool(m[1])]
+	}
+	return false
+}
+
+type edit struct {
+	start, end int
+	text       string
+}
+
+// editor rewrites a source file with edits based on positions of the AST.
+type editor struct {
+	src   []byte
+	fset  *token.FileSet
+	edits []edit
+}
+
+func (e *editor) offset(pos token.Pos) int {
+	return e.fset.Position(pos).Offset
+}
+
+// lineStart returns an offset of the beginning of the line that contains pos.
+func (e *editor) lineStart(pos token.Pos) int {
+	return bytes.LastIndexByte(e.src[:e.offset(pos)], '\n') + 1
+}
+
+// lineEnd returns an offset of the next line of pos.
+func (e *editor) lineEnd(pos token.Pos) int {
+	off := e.offset(pos)
+	if i := bytes.IndexByte(e.src[off:], '\n'); i >= 0 {
+		return off + i + 1
+	}
+	return len(e.src)
+}
+
+func (e *editor) replace(start, end int, text string) {
+	e.edits = append(e.edits, edit{start: start, end: end, text: text})
+}
+
+// drop discards edits within the range, that will be replaced as a whole.
+func (e *editor) drop(start, end int) {
+	edits := e.edits[:0]
+	for _, ed := range e.edits {
+		if ed.start < start || ed.end > end {
+			edits = append(edits, ed)
+		}
+	}
+	e.edits = edits
+}
+
+func (e *editor) removeLines(start, end token.Pos) {
+	e.replace(e.lineStart(start), e.lineEnd(end), "")
+}
+
+// removeLegacyBuildTag removes `// +build` lines from the file that has `//go:build` constraint.
+func (e *editor) removeLegacyBuildTag(f *ast.File) {
+	var (
+		hasGoBuild bool
+		legacy     []*ast.Comment
+	)
+	for _, cg := range f.Comments {
+		if cg.Pos() >= f.Package {
+			break
+		}
+		for _, c := range cg.List {
+			switch {
+			case strings.HasPrefix(c.Text, "//go:build "):
+				hasGoBuild = true
+			case strings.HasPrefix(c.Text, "// +build "):
+				legacy = append(legacy, c)
+			}
+		}
+	}
+	if !hasGoBuild {
+		return
+	}
+	for _, c := range legacy {
+		e.removeLines(c.Pos(), c.End())
+	}
+}
+
+func (e *editor) updateGroupAnnotation(s *ast.ImportSpec, groups []string) {
+	var annotation string
+	if len(groups) > 0 {
+		annotation = "// " + groupAnnotation + strings.Join(groups, ",")
+	}
+
+	for _, cg := range []*ast.CommentGroup{s.Doc, s.Comment} {
+		if cg == nil {
+			continue
+		}
+		for _, c := range cg.List {
+			if !strings.HasPrefix(strings.TrimSpace(strings.TrimPrefix(c.Text, "//")), groupAnnotation) {
+				continue
+			}
+			switch {
+			case annotation == "" && cg == s.Doc:
+				e.removeLines(c.Pos(), c.End())
+			case annotation == "":
+				e.replace(e.offset(s.End()), e.offset(c.End()), "")
+			case c.Text != annotation:
+				e.replace(e.offset(c.Pos()), e.offset(c.End()), annotation)
+			}
+			// only the first annotation is updated, others are merged by the parser
+			annotation = ""
+			groups = nil
+		}
+	}
+
+	switch {
+	case len(groups) == 0:
+	case s.Comment == nil:
+		e.replace(e.offset(s.End()), e.offset(s.End()), " "+annotation)
+	default:
+		start := e.lineStart(importSpecStart(s))
+		e.replace(start, start, "\t"+annotation+"\n")
+	}
+}
+
+func (e *editor) insertImports(f *ast.File, m *Manifest, specs string) {
+	for _, decl := range f.Decls {
+		d, ok := decl.(*ast.GenDecl)
+		if !ok || d.Tok != token.IMPORT {
+			continue
+		}
+		if !d.Lparen.IsValid() {
+			// import _ "github.com/golang/mock/mockgen"
+			// The spec may have been removed or updated, so the declaration is rewritten as a whole.
+			start, end := e.lineStart(d.Pos()), e.lineEnd(d.End())
+			e.drop(start, end)
+			e.replace(start, end, "import (\n"+keptImportSpec(d.Specs[0].(*ast.ImportSpec), m)+specs+")\n")
+			return
+		}
+		rparen := e.offset(d.Rparen)
+		if start := e.lineStart(d.Rparen); len(bytes.TrimSpace(e.src[start:rparen])) == 0 {
+			e.replace(start, start, specs)
+		} else {
+			e.replace(rparen, rparen, "\n"+specs)
+		}
+		return
+	}
+
+	end := e.lineEnd(f.Name.End())
+	e.replace(end, end, "\n// tool dependencies\nimport (\n"+specs+")\n")
+}
+
+// keptImportSpec returns lines of the import spec if its tool is still in the manifest.
+// Comments other than the group annotation are kept above the spec.
+func keptImportSpec(s *ast.ImportSpec, m *Manifest) string {
+	pkg, err := strconv.Unquote(s.Path.Value)
+	if err != nil {
+		return ""
+	}
+	t := Tool(pkg)
+	if current, ok := m.FindTool(t.Name()); !ok || current != t {
+		return ""
+	}
+	var lines string
+	if s.Comment != nil {
+		for _, c := range s.Comment.List {
+			if !strings.HasPrefix(strings.TrimSpace(strings.TrimPrefix(c.Text, "//")), groupAnnotation) {
+				lines += "\t" + c.Text + "\n"
+			}
+		}
+	}
+	return lines + "\t" + importSpecLine(t, m.Groups(t)) + "\n"
+}
+
+func (e *editor) appendDirectives(f *ast.File, directives string) {
+	src := e.src
+	var prefix string
+	if len(src) > 0 && src[len(src)-1] != '\n' {
+		prefix = "\n"
+	}
+	// directives are appended into the trailing comment, such as "If you want to use tools, ..."
+	if n := len(f.Comments); n == 0 || len(bytes.TrimSpace(src[e.offset(f.Comments[n-1].End()):])) != 0 {
+		prefix += "\n"
+	}
+	e.replace(len(src), len(src), prefix+directives)
+}
+
+func (e *editor) apply() []byte {
+	sort.SliceStable(e.edits, func(i, j int) bool { return e.edits[i].start < e.edits[j].start })
+
+	buf := new(bytes.Buffer)
+	var last int
+	for _, ed := range e.edits {
+		if ed.start < last {
+			// overlapped with a removed range
+			continue
+		}
+		buf.Write(e.src[last:ed.start])
+		buf.WriteString(ed.text)
+		last = ed.end
+	}
+	buf.Write(e.src[last:])
+	return buf.Bytes()
+}
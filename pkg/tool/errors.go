@@ -1,11 +1,64 @@
 package tool
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
+
+	"github.com/izumin5210/gex/pkg/manager"
+)
+
+var (
+	// ErrToolNotFound is returned when the tool is not managed in the manifest.
+	// Errors of the type *ToolNotFoundError match it with errors.Is.
+	ErrToolNotFound = errors.New("tool not found")
+	// ErrManifestNotFound is returned when the manifest file does not exist.
+	// Errors of the type *ManifestNotFoundError match it with errors.Is.
+	ErrManifestNotFound = errors.New("manifest not found")
+	// ErrManagerUnknown is returned when the dependencies management tool is unknown or not registered.
+	ErrManagerUnknown = manager.ErrUnknownType
 )
 
+// ToolNotFoundError is returned when the tool is not managed in the manifest.
+type ToolNotFoundError struct {
+	Name string
+	// Suggestions contains names of tools in the manifest that are similar to Name.
+	Suggestions []string
+	// Packages contains packages of commands named Name, that are found in the dependencies of the project.
+	Packages []string
+}
+
+func (e *ToolNotFoundError) Error() string {
+	msg := fmt.Sprintf("failed to find the tool %q", e.Name)
+	switch {
+	case len(e.Suggestions) == 1:
+		msg += fmt.Sprintf(", did you mean %s?", e.Suggestions[0])
+	case len(e.Suggestions) > 1:
+		msg += fmt.Sprintf(", did you mean one of %s?", strings.Join(e.Suggestions, ", "))
+	case len(e.Packages) == 1:
+		msg += fmt.Sprintf(", run `gex --add %s` to add it", e.Packages[0])
+	case len(e.Packages) > 1:
+		msg += fmt.Sprintf(", run `gex --add` with one of %s to add it", strings.Join(e.Packages, ", "))
+	}
+	return msg
+}
+
+// Is returns true if target is ErrToolNotFound.
+func (e *ToolNotFoundError) Is(target error) bool { return target == ErrToolNotFound }
+
+// ManifestNotFoundError is returned when the manifest file does not exist.
+type ManifestNotFoundError struct {
+	Path string
+}
+
+func (e *ManifestNotFoundError) Error() string {
+	return fmt.Sprintf("could not find %s", e.Path)
+}
+
+// Is returns true if target is ErrManifestNotFound.
+func (e *ManifestNotFoundError) Is(target error) bool { return target == ErrManifestNotFound }
+
 type BuildError struct {
 	Tool Tool
 	Err  error
@@ -0,0 +1,74 @@
+//go:build go1.13
+// +build go1.13
+
+package tool_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/spf13/afero"
+
+	"github.com/izumin5210/gex/pkg/manager"
+	"github.com/izumin5210/gex/pkg/tool"
+)
+
+func TestRepository_Errors(t *testing.T) {
+	ctx := context.Background()
+	rootDir := "/home/src/awesomeapp"
+
+	createRepo := func(t *testing.T, tools ...tool.Tool) tool.Repository {
+		t.Helper()
+		fs := afero.NewMemMapFs()
+		cfg := &tool.Config{
+			FS:           fs,
+			RootDir:      rootDir,
+			WorkingDir:   rootDir,
+			ManifestName: "tools.go",
+			BinDirName:   "bin",
+		}
+		if len(tools) > 0 {
+			err := tool.NewWriter(fs).Write(cfg.ManifestPath(), tool.NewManifest(tools, manager.TypeModules))
+			if err != nil {
+				t.Fatalf("failed to write the manifest: %v", err)
+			}
+		}
+		return tool.NewRepository(nil, nil, manager.TypeModules, cfg)
+	}
+
+	t.Run("manifest not found", func(t *testing.T) {
+		err := createRepo(t).Run(ctx, "mockgen")
+
+		if !errors.Is(err, tool.ErrManifestNotFound) {
+			t.Errorf("Run() should return ErrManifestNotFound, but returned %v", err)
+		}
+		var nfErr *tool.ManifestNotFoundError
+		if !errors.As(err, &nfErr) || nfErr.Path != rootDir+"/tools.go" {
+			t.Errorf("Run() should return *ManifestNotFoundError with the path, but returned %v", err)
+		}
+	})
+
+	t.Run("tool not found", func(t *testing.T) {
+		err := createRepo(t, "github.com/golang/mock/mockgen").Run(ctx, "golint")
+
+		if !errors.Is(err, tool.ErrToolNotFound) {
+			t.Errorf("Run() should return ErrToolNotFound, but returned %v", err)
+		}
+		if errors.Is(err, tool.ErrManifestNotFound) {
+			t.Errorf("Run() should not return ErrManifestNotFound")
+		}
+		var nfErr *tool.ToolNotFoundError
+		if !errors.As(err, &nfErr) || nfErr.Name != "golint" {
+			t.Errorf("Run() should return *ToolNotFoundError with the name, but returned %v", err)
+		}
+	})
+
+	t.Run("unknown manager", func(t *testing.T) {
+		_, err := manager.ParseType("glide")
+
+		if !errors.Is(err, tool.ErrManagerUnknown) {
+			t.Errorf("ParseType() should return ErrManagerUnknown, but returned %v", err)
+		}
+	})
+}
@@ -0,0 +1,226 @@
+package tool
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"strings"
+	"time"
+)
+
+// Event is a notification of the progress of operations on tools.
+// Observers receive values of the types defined in this file.
+type Event interface {
+	isEvent()
+}
+
+// SyncStarted is sent before dependencies are synchronized with the manifest.
+type SyncStarted struct{}
+
+// SyncFinished is sent after dependencies are synchronized.
+type SyncFinished struct {
+	Duration time.Duration
+	Err      error
+}
+
+// AddStarted is sent before packages are added to the manifest.
+type AddStarted struct {
+	Packages []string
+}
+
+// RemoveStarted is sent before packages are removed from the manifest.
+type RemoveStarted struct {
+	Packages []string
+}
+
+// DownloadStarted is sent before sources of the tools are downloaded.
+type DownloadStarted struct {
+	Tools []Tool
+}
+
+// DownloadFinished is sent after sources are downloaded.
+type DownloadFinished struct {
+	Tools    []Tool
+	Duration time.Duration
+	Err      error
+}
+
+// BuildStarted is sent before the tool is built.
+type BuildStarted struct {
+	Tool    Tool
+	BinPath string
+}
+
+// BuildFinished is sent after the tool is built. Err is non-nil if the build failed.
+type BuildFinished struct {
+	Tool     Tool
+	BinPath  string
+	Duration time.Duration
+	Err      error
+}
+
+// BuildOutput is sent for each line of stderr of `go build` if Config.CaptureBuildOutput is true.
+type BuildOutput struct {
+	Tool Tool
+	Line string
+}
+
+// CacheHit is sent when the binary of the tool has already been built.
+type CacheHit struct {
+	Tool    Tool
+	BinPath string
+}
+
+// ExecStarted is sent before the tool is executed.
+type ExecStarted struct {
+	Tool    Tool
+	BinPath string
+	Args    []string
+}
+
+// GenerateStarted is sent before `go generate` is run with the tools referenced from the packages.
+type GenerateStarted struct {
+	Tools []Tool
+}
+
+// ShimWritten is sent after a shim of the tool is written.
+type ShimWritten struct {
+	Tool Tool
+	Path string
+}
+
+// ExportStarted is sent before the tools are built and written into a bundle.
+type ExportStarted struct {
+	Tools []Tool
+}
+
+// ToolImported is sent after the binary of the tool is installed from a bundle.
+type ToolImported struct {
+	Tool    Tool
+	BinPath string
+}
+
+// CommandSearchFailed is sent when the dependencies of the project could not be searched for the command missing in the manifest.
+type CommandSearchFailed struct {
+	Name string
+	Err  error
+}
+
+func (SyncStarted) isEvent()         {}
+func (SyncFinished) isEvent()        {}
+func (AddStarted) isEvent()          {}
+func (RemoveStarted) isEvent()       {}
+func (DownloadStarted) isEvent()     {}
+func (DownloadFinished) isEvent()    {}
+func (BuildStarted) isEvent()        {}
+func (BuildFinished) isEvent()       {}
+func (BuildOutput) isEvent()         {}
+func (CacheHit) isEvent()            {}
+func (ExecStarted) isEvent()         {}
+func (GenerateStarted) isEvent()     {}
+func (ShimWritten) isEvent()         {}
+func (ExportStarted) isEvent()       {}
+func (ToolImported) isEvent()        {}
+func (CommandSearchFailed) isEvent() {}
+
+// MultiObserver creates an Observer that sends events to all the observers.
+func MultiObserver(observers ...Observer) Observer {
+	return ObserverFunc(func(ev Event) {
+		for _, o := range observers {
+			o.OnEvent(ev)
+		}
+	})
+}
+
+// lineWriter is an io.Writer that calls the function for each line.
+type lineWriter struct {
+	buf bytes.Buffer
+	f   func(line string)
+}
+
+func newLineWriter(f func(line string)) *lineWriter {
+	return &lineWriter{f: f}
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	for {
+		i := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if i < 0 {
+			break
+		}
+		line := string(w.buf.Next(i + 1))
+		w.f(strings.TrimRight(line, "\r\n"))
+	}
+	return len(p), nil
+}
+
+// Flush calls the function with the last line that is not terminated with a newline.
+func (w *lineWriter) Flush() {
+	if w.buf.Len() > 0 {
+		w.f(w.buf.String())
+		w.buf.Reset()
+	}
+}
+
+// Observer receives events from Repository.
+// OnEvent can be called concurrently since tools are built in parallel.
+type Observer interface {
+	OnEvent(ev Event)
+}
+
+// ObserverFunc is an adapter to use ordinary functions as Observer.
+type ObserverFunc func(ev Event)
+
+// OnEvent calls f(ev).
+func (f ObserverFunc) OnEvent(ev Event) { f(ev) }
+
+// NewLogObserver creates an Observer that writes events into the logger.
+// It is used if Config.Observer is nil.
+func NewLogObserver(l *log.Logger) Observer {
+	if l == nil {
+		l = log.New(ioutil.Discard, "", 0)
+	}
+	return ObserverFunc(func(ev Event) {
+		switch ev := ev.(type) {
+		case SyncStarted:
+			l.Println("sync dependencies")
+		case SyncFinished:
+			if ev.Err != nil {
+				l.Println("failed to sync dependencies:", ev.Err)
+			}
+		case AddStarted:
+			l.Println("add", strings.Join(ev.Packages, ", "))
+		case RemoveStarted:
+			l.Println("remove", strings.Join(ev.Packages, ", "))
+		case DownloadStarted:
+			l.Println("download sources of", len(ev.Tools), "tool(s)")
+		case DownloadFinished:
+			if ev.Err != nil {
+				l.Println("failed to download sources:", ev.Err)
+			}
+		case BuildStarted:
+			l.Println("build", ev.Tool)
+		case BuildFinished:
+			if ev.Err != nil {
+				l.Println("failed to build", ev.Tool, "in", ev.Duration.Round(time.Millisecond))
+			} else {
+				l.Println("built", ev.Tool, "in", ev.Duration.Round(time.Millisecond))
+			}
+		case CacheHit:
+			l.Println("use", ev.BinPath)
+		case ExecStarted:
+			l.Println("run", strings.Join(append([]string{ev.Tool.Name()}, ev.Args...), " "))
+		case GenerateStarted:
+			l.Println("generate with", len(ev.Tools), "tool(s)")
+		case ShimWritten:
+			l.Println("write shim", ev.Path)
+		case ExportStarted:
+			l.Println("export", len(ev.Tools), "tool(s)")
+		case ToolImported:
+			l.Println("import", ev.Tool)
+		case CommandSearchFailed:
+			l.Println("failed to search commands:", ev.Err)
+		}
+	})
+}
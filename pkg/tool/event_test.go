@@ -0,0 +1,129 @@
+package tool_test
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/spf13/afero"
+
+	"github.com/izumin5210/gex/pkg/manager"
+	"github.com/izumin5210/gex/pkg/tool"
+)
+
+type fakeManager struct {
+	manager.Interface
+	fs       afero.Fs
+	versions map[string]string
+	added    [][]string
+}
+
+func (m *fakeManager) Add(ctx context.Context, pkgs []string, verbose bool) error {
+	m.added = append(m.added, pkgs)
+	return nil
+}
+
+func (m *fakeManager) Build(ctx context.Context, binPath, pkg string, opts manager.BuildOptions) error {
+	return afero.WriteFile(m.fs, binPath, []byte(pkg), 0755)
+}
+
+func (m *fakeManager) Download(ctx context.Context, pkgs []string, verbose bool) error { return nil }
+
+func (m *fakeManager) Sync(ctx context.Context, verbose bool) error { return nil }
+
+func (m *fakeManager) Versions(ctx context.Context, pkgs []string) (map[string]string, error) {
+	return m.versions, nil
+}
+
+type recordingObserver struct {
+	sync.Mutex
+	events []tool.Event
+}
+
+func (o *recordingObserver) OnEvent(ev tool.Event) {
+	o.Lock()
+	defer o.Unlock()
+	o.events = append(o.events, ev)
+}
+
+func TestRepository_Observer(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	obs := new(recordingObserver)
+	cfg := &tool.Config{
+		FS:           fs,
+		RootDir:      "/home/src/awesomeapp",
+		ManifestName: "tools.go",
+		BinDirName:   "bin",
+		Log:          log.New(ioutil.Discard, "", 0),
+		Observer:     obs,
+	}
+	err := tool.NewWriter(fs).Write(cfg.ManifestPath(), tool.NewManifest([]tool.Tool{
+		"github.com/golang/mock/mockgen",
+		"golang.org/x/lint/golint",
+	}, manager.TypeModules))
+	if err != nil {
+		t.Fatalf("failed to write the manifest: %v", err)
+	}
+
+	repo := tool.NewRepository(nil, &fakeManager{fs: fs}, manager.TypeModules, cfg)
+	ctx := context.Background()
+
+	_, err = repo.Build(ctx, "github.com/golang/mock/mockgen")
+	if err != nil {
+		t.Fatalf("Build() returned an error: %v", err)
+	}
+	_, err = repo.Build(ctx, "github.com/golang/mock/mockgen")
+	if err != nil {
+		t.Fatalf("Build() returned an error: %v", err)
+	}
+	err = repo.Download(ctx)
+	if err != nil {
+		t.Fatalf("Download() returned an error: %v", err)
+	}
+	err = repo.Remove(ctx, "golang.org/x/lint/golint")
+	if err != nil {
+		t.Fatalf("Remove() returned an error: %v", err)
+	}
+	err = repo.Add(ctx, "golang.org/x/tools/cmd/stringer")
+	if err != nil {
+		t.Fatalf("Add() returned an error: %v", err)
+	}
+	err = repo.WriteShims(ctx)
+	if err != nil {
+		t.Fatalf("WriteShims() returned an error: %v", err)
+	}
+
+	binPath := cfg.BinPath("mockgen")
+	stringerPath := cfg.BinPath("stringer")
+	tools := []tool.Tool{"github.com/golang/mock/mockgen", "golang.org/x/lint/golint"}
+	want := []tool.Event{
+		tool.BuildStarted{Tool: "github.com/golang/mock/mockgen", BinPath: binPath},
+		tool.BuildFinished{Tool: "github.com/golang/mock/mockgen", BinPath: binPath},
+		tool.CacheHit{Tool: "github.com/golang/mock/mockgen", BinPath: binPath},
+		tool.DownloadStarted{Tools: tools},
+		tool.DownloadFinished{Tools: tools},
+		tool.RemoveStarted{Packages: []string{"golang.org/x/lint/golint"}},
+		tool.SyncStarted{},
+		tool.SyncFinished{},
+		tool.AddStarted{Packages: []string{"golang.org/x/tools/cmd/stringer"}},
+		tool.SyncStarted{},
+		tool.SyncFinished{},
+		tool.BuildStarted{Tool: "golang.org/x/tools/cmd/stringer", BinPath: stringerPath},
+		tool.BuildFinished{Tool: "golang.org/x/tools/cmd/stringer", BinPath: stringerPath},
+		tool.ShimWritten{Tool: "github.com/golang/mock/mockgen", Path: binPath},
+		tool.ShimWritten{Tool: "golang.org/x/tools/cmd/stringer", Path: stringerPath},
+	}
+
+	opts := []cmp.Option{
+		cmpopts.IgnoreFields(tool.BuildFinished{}, "Duration"),
+		cmpopts.IgnoreFields(tool.SyncFinished{}, "Duration"),
+		cmpopts.IgnoreFields(tool.DownloadFinished{}, "Duration"),
+	}
+	if diff := cmp.Diff(want, obs.events, opts...); diff != "" {
+		t.Errorf("received events differ: (-want +got)\n%s", diff)
+	}
+}
@@ -0,0 +1,45 @@
+package tool
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+// writeFile writes data to a temporary file and renames it to path,
+// so that other processes never read a partially written file.
+func writeFile(fs afero.Fs, path string, data []byte, perm os.FileMode) error {
+	f, err := afero.TempFile(fs, filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer fs.Remove(f.Name())
+
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	err = fs.Chmod(f.Name(), perm)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(fs.Rename(f.Name(), path))
+}
+
+// tempPath returns a path of a hidden file that does not exist in the same directory as path.
+func tempPath(fs afero.Fs, path string) (string, error) {
+	f, err := afero.TempFile(fs, filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	if err := f.Close(); err != nil {
+		return "", errors.WithStack(err)
+	}
+	return f.Name(), errors.WithStack(fs.Remove(f.Name()))
+}
@@ -0,0 +1,66 @@
+package tool
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Locker serializes operations that modify the manifest and binaries across processes.
+type Locker interface {
+	// Lock blocks until the lock is acquired, and returns a function to release it.
+	Lock(ctx context.Context) (unlock func(), err error)
+}
+
+// NewFileLocker creates a Locker that takes an advisory lock on the file.
+// Lock returns an error if the lock could not be acquired within the timeout.
+func NewFileLocker(path string, timeout time.Duration) Locker {
+	return &fileLocker{path: path, timeout: timeout}
+}
+
+type fileLocker struct {
+	path    string
+	timeout time.Duration
+}
+
+const lockRetryInterval = 100 * time.Millisecond
+
+func (l *fileLocker) Lock(ctx context.Context) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	f, err := os.OpenFile(l.path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if l.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.timeout)
+		defer cancel()
+	}
+
+	for {
+		ok, err := tryLockFile(f)
+		if err != nil {
+			f.Close()
+			return nil, errors.Wrapf(err, "failed to lock %s", l.path)
+		}
+		if ok {
+			return func() {
+				_ = unlockFile(f)
+				_ = f.Close()
+			}, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			f.Close()
+			return nil, errors.Errorf("timed out waiting for %s, other gex processes may be running", l.path)
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
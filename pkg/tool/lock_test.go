@@ -0,0 +1,41 @@
+package tool_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/izumin5210/gex/pkg/tool"
+)
+
+func TestFileLocker_Lock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gex-lock")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "bin", ".gex.lock")
+	ctx := context.Background()
+
+	unlock, err := tool.NewFileLocker(path, time.Second).Lock(ctx)
+	if err != nil {
+		t.Fatalf("Lock() returned an error: %v", err)
+	}
+
+	_, err = tool.NewFileLocker(path, 200*time.Millisecond).Lock(ctx)
+	if err == nil {
+		t.Error("Lock() should return an error while the lock is held")
+	}
+
+	unlock()
+
+	unlock, err = tool.NewFileLocker(path, 200*time.Millisecond).Lock(ctx)
+	if err != nil {
+		t.Fatalf("Lock() returned an error after the lock is released: %v", err)
+	}
+	unlock()
+}
@@ -1,6 +1,7 @@
 package tool
 
 import (
+	"path"
 	"sort"
 
 	"github.com/izumin5210/gex/pkg/manager"
@@ -9,6 +10,7 @@ import (
 // Manifest contains tool list
 type Manifest struct {
 	toolMap     map[string]Tool
+	groupMap    map[string][]string
 	managerType manager.Type
 }
 
@@ -18,7 +20,7 @@ func NewManifest(tools []Tool, mType manager.Type) *Manifest {
 	for _, t := range tools {
 		toolMap[t.Name()] = t
 	}
-	return &Manifest{toolMap: toolMap, managerType: mType}
+	return &Manifest{toolMap: toolMap, groupMap: make(map[string][]string), managerType: mType}
 }
 
 func (m *Manifest) ManagerType() manager.Type { return m.managerType }
@@ -28,6 +30,66 @@ func (m *Manifest) AddTool(tool Tool) {
 	m.toolMap[tool.Name()] = tool
 }
 
+// RemoveTool removes the tool from the manifest. It returns false if the manifest does not contain the tool.
+func (m *Manifest) RemoveTool(tool Tool) bool {
+	if t, ok := m.toolMap[tool.Name()]; !ok || t != tool {
+		return false
+	}
+	delete(m.toolMap, tool.Name())
+	delete(m.groupMap, tool.Name())
+	return true
+}
+
+// Groups returns names of groups that the tool belongs to.
+func (m *Manifest) Groups(tool Tool) []string {
+	return m.groupMap[tool.Name()]
+}
+
+// SetGroups makes the tool belong to the groups. The tool is removed from all groups if no groups are given.
+func (m *Manifest) SetGroups(tool Tool, groups ...string) {
+	if len(groups) == 0 {
+		delete(m.groupMap, tool.Name())
+		return
+	}
+	gs := make([]string, 0, len(groups))
+	seen := make(map[string]struct{}, len(groups))
+	for _, g := range groups {
+		if _, ok := seen[g]; ok || g == "" {
+			continue
+		}
+		seen[g] = struct{}{}
+		gs = append(gs, g)
+	}
+	sort.Strings(gs)
+	m.groupMap[tool.Name()] = gs
+}
+
+// ToolsInGroups returns tools that belong to any of the groups. It returns all tools if no groups are given.
+func (m *Manifest) ToolsInGroups(groups ...string) []Tool {
+	tools := m.Tools()
+	if len(groups) == 0 {
+		return tools
+	}
+	filtered := make([]Tool, 0, len(tools))
+	for _, t := range tools {
+		if m.inGroups(t, groups) {
+			filtered = append(filtered, t)
+		}
+	}
+	return filtered
+}
+
+func (m *Manifest) inGroups(t Tool, groups []string) bool {
+	for _, g := range m.Groups(t) {
+		for _, want := range groups {
+			if g == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // FindTool returns a tool by a name.
 func (m *Manifest) FindTool(name string) (t Tool, ok bool) {
 	t, ok = m.toolMap[name]
@@ -49,6 +111,14 @@ func (m *Manifest) Tools() []Tool {
 	return ts
 }
 
+func (m *Manifest) findReferencedTool(ref Reference) (Tool, bool) {
+	if ref.Package != "" {
+		t, ok := m.toolMap[path.Base(ref.Package)]
+		return t, ok && t == Tool(ref.Package)
+	}
+	return m.FindTool(ref.Name)
+}
+
 func (m *Manifest) addTool(t Tool) {
 	m.toolMap[t.Name()] = t
 }
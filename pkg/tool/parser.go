@@ -1,9 +1,11 @@
 package tool
 
 import (
+	"go/ast"
 	"go/parser"
 	"go/token"
 	"strconv"
+	"strings"
 
 	"github.com/izumin5210/gex/pkg/manager"
 	"github.com/pkg/errors"
@@ -35,18 +37,49 @@ func (p *parserImpl) Parse(path string) (*Manifest, error) {
 	}
 
 	fset := token.NewFileSet()
-	f, err := parser.ParseFile(fset, "", string(data), parser.ImportsOnly)
+	f, err := parser.ParseFile(fset, "", string(data), parser.ImportsOnly|parser.ParseComments)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to parse %q", path)
 	}
 
 	tools := make([]Tool, 0, len(f.Imports))
+	groups := make(map[Tool][]string)
 
 	for _, s := range f.Imports {
 		if pkg, err := strconv.Unquote(s.Path.Value); err == nil {
 			tools = append(tools, Tool(pkg))
+			groups[Tool(pkg)] = append(parseGroupAnnotation(s.Doc), parseGroupAnnotation(s.Comment)...)
 		}
 	}
 
-	return NewManifest(tools, p.mType), nil
+	m := NewManifest(tools, p.mType)
+	for t, gs := range groups {
+		m.SetGroups(t, gs...)
+	}
+
+	return m, nil
+}
+
+// groupAnnotation is a prefix of comments on import specs to assign the tool to groups.
+//
+//	_ "github.com/golangci/golangci-lint/cmd/golangci-lint" // gex:group=lint,ci
+const groupAnnotation = "gex:group="
+
+func parseGroupAnnotation(cg *ast.CommentGroup) []string {
+	if cg == nil {
+		return nil
+	}
+	var groups []string
+	for _, c := range cg.List {
+		text := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
+		if !strings.HasPrefix(text, groupAnnotation) {
+			continue
+		}
+		for _, g := range strings.Split(strings.TrimPrefix(text, groupAnnotation), ",") {
+			if g = strings.TrimSpace(g); g != "" {
+				groups = append(groups, g)
+			}
+		}
+	}
+	return groups
 }
@@ -55,3 +55,53 @@ import (
 		t.Errorf("tool differs: (-want +got)\n%s", diff)
 	}
 }
+
+func TestParser_Parse_Groups(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	parser := tool.NewParser(fs, manager.TypeModules)
+
+	var (
+		toolsGo = `// +build tools
+
+package tools
+
+import (
+	_ "github.com/golang/mock/mockgen" // gex:group=codegen
+	// gex:group=lint, ci
+	_ "github.com/golangci/golangci-lint/cmd/golangci-lint" // gex:group=release
+	// linter
+	_ "golang.org/x/lint/golint"
+)
+`
+	)
+	path := "/home/src/awesomeapp/tools.go"
+
+	err := afero.WriteFile(fs, path, []byte(toolsGo), 0644)
+	if err != nil {
+		t.Fatalf("faield to write %s: %v", path, err)
+	}
+
+	out, err := parser.Parse(path)
+	if err != nil {
+		t.Fatalf("Parse() returned an error: %v", err)
+	}
+
+	wantGroups := map[tool.Tool][]string{
+		"github.com/golang/mock/mockgen":                      {"codegen"},
+		"github.com/golangci/golangci-lint/cmd/golangci-lint": {"ci", "lint", "release"},
+		"golang.org/x/lint/golint":                            nil,
+	}
+	for tl, want := range wantGroups {
+		if diff := cmp.Diff(want, out.Groups(tl)); diff != "" {
+			t.Errorf("groups of %s differs: (-want +got)\n%s", tl, diff)
+		}
+	}
+
+	wantTools := []tool.Tool{
+		"github.com/golang/mock/mockgen",
+		"github.com/golangci/golangci-lint/cmd/golangci-lint",
+	}
+	if diff := cmp.Diff(wantTools, out.ToolsInGroups("codegen", "ci")); diff != "" {
+		t.Errorf("ToolsInGroups() differs: (-want +got)\n%s", diff)
+	}
+}
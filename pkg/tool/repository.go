@@ -2,21 +2,34 @@ package tool
 
 import (
 	"context"
+	"io"
+	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
+	"github.com/spf13/afero"
 
 	"github.com/izumin5210/gex/pkg/manager"
 )
 
 // Repository is an interface for managing and operating tools
 type Repository interface {
-	List(ctx context.Context) ([]Tool, error)
+	List(ctx context.Context, groups ...string) ([]Tool, error)
+	Status(ctx context.Context, groups ...string) ([]*ToolStatus, error)
 	Add(ctx context.Context, pkgs ...string) error
+	Remove(ctx context.Context, pkgs ...string) error
 	Build(ctx context.Context, t Tool) (string, error)
-	BuildAll(ctx context.Context) error
+	BuildAll(ctx context.Context, groups ...string) error
+	Download(ctx context.Context, groups ...string) error
+	Scan(ctx context.Context) (*ScanReport, error)
+	Generate(ctx context.Context, patterns ...string) error
+	WriteShims(ctx context.Context) error
 	Run(ctx context.Context, name string, args ...string) error
+	Export(ctx context.Context, w io.Writer) error
+	Import(ctx context.Context, r io.Reader) error
+	ExportScript(ctx context.Context, w io.Writer, format ScriptFormat) error
 }
 
 type repositoryImpl struct {
@@ -26,6 +39,7 @@ type repositoryImpl struct {
 	executor    manager.Executor
 	manager     manager.Interface
 	managerType manager.Type
+	logObserver Observer
 }
 
 // NewRepository creates a new Repository instance.
@@ -37,30 +51,42 @@ func NewRepository(executor manager.Executor, manager manager.Interface, manager
 		executor:    executor,
 		manager:     manager,
 		managerType: managerType,
+		logObserver: NewLogObserver(cfg.Log),
 	}
 }
 
-func (r *repositoryImpl) List(ctx context.Context) ([]Tool, error) {
+func (r *repositoryImpl) List(ctx context.Context, groups ...string) ([]Tool, error) {
 	m, err := r.getManifest()
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
 
-	return m.Tools(), nil
+	return findToolsInGroups(m, groups)
 }
 
 func (r *repositoryImpl) Add(ctx context.Context, pkgs ...string) error {
-	r.Log.Println("add", strings.Join(pkgs, ", "))
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer unlock()
 
+	r.notify(AddStarted{Packages: pkgs})
+
+	// sources are fetched only with Add in GOPATH mode, since they are not resolved by Sync and Build
+	add := r.managerType == manager.TypeGOPATH
 	for _, pkg := range pkgs {
 		if strings.Contains(pkg, "@") {
-			err := r.manager.Add(ctx, pkgs, r.Verbose)
-			if err != nil {
-				return errors.Wrap(err, "failed to add tools")
-			}
+			add = true
 			break
 		}
 	}
+	if add {
+		err := r.manager.Add(ctx, pkgs, r.Verbose)
+		if err != nil {
+			return errors.Wrap(err, "failed to add tools")
+		}
+	}
 
 	m, err := r.parser.Parse(r.ManifestPath())
 	if err != nil {
@@ -81,13 +107,13 @@ func (r *repositoryImpl) Add(ctx context.Context, pkgs ...string) error {
 		return errors.Wrap(err, "failed to write a manifest file")
 	}
 
-	err = r.manager.Sync(ctx, r.Verbose)
+	err = r.sync(ctx)
 	if err != nil {
 		return errors.Wrap(err, "failed to sync packages")
 	}
 
 	for _, t := range tools {
-		_, err = r.Build(ctx, t)
+		_, err = r.build(ctx, t)
 		if err != nil {
 			return errors.WithStack(err)
 		}
@@ -95,39 +121,138 @@ func (r *repositoryImpl) Add(ctx context.Context, pkgs ...string) error {
 	return nil
 }
 
+func (r *repositoryImpl) Remove(ctx context.Context, pkgs ...string) error {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer unlock()
+
+	r.notify(RemoveStarted{Packages: pkgs})
+
+	m, err := r.getManifest()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	for _, pkg := range pkgs {
+		t := Tool(pkg)
+		if !m.RemoveTool(t) {
+			return errors.WithStack(&ToolNotFoundError{Name: pkg})
+		}
+		for _, binPath := range []string{r.BinPath(t.Name()), r.ShimmedBinPath(t.Name())} {
+			err = r.FS.RemoveAll(binPath)
+			if err != nil {
+				return errors.Wrapf(err, "failed to remove the binary of %s", t)
+			}
+		}
+	}
+
+	err = r.writer.Write(r.ManifestPath(), m)
+	if err != nil {
+		return errors.Wrap(err, "failed to write a manifest file")
+	}
+
+	err = r.sync(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to sync packages")
+	}
+
+	return nil
+}
+
 func (r *repositoryImpl) Build(ctx context.Context, t Tool) (string, error) {
-	binPath := r.BinPath(t.Name())
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	defer unlock()
+
+	return r.build(ctx, t)
+}
+
+func (r *repositoryImpl) build(ctx context.Context, t Tool) (string, error) {
+	binPath, err := r.binPath(t)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
 
 	if st, err := r.FS.Stat(binPath); err != nil {
-		r.Log.Println("build", t)
-		err := r.manager.Build(ctx, binPath, string(t), r.Verbose)
+		r.notify(BuildStarted{Tool: t, BinPath: binPath})
+		start := time.Now()
+		err = r.buildTo(ctx, binPath, t)
+		r.notify(BuildFinished{Tool: t, BinPath: binPath, Duration: time.Since(start), Err: err})
 		if err != nil {
 			return "", errors.Wrapf(err, "failed to build %s", t)
 		}
 	} else if st.IsDir() {
 		return "", errors.Errorf("%q is a directory", t.Name())
+	} else {
+		r.notify(CacheHit{Tool: t, BinPath: binPath})
 	}
 
 	return binPath, nil
 }
 
-func (r *repositoryImpl) BuildAll(ctx context.Context) error {
+// buildTo builds the tool into a temporary file and renames it,
+// so that other processes never execute a partially written binary.
+func (r *repositoryImpl) buildTo(ctx context.Context, binPath string, t Tool) error {
+	err := r.FS.MkdirAll(filepath.Dir(binPath), 0755)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	tmpPath, err := tempPath(r.FS, binPath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer r.FS.Remove(tmpPath)
+
+	opts := manager.BuildOptions{Verbose: r.Verbose, Flags: r.BuildFlags}
+	if r.CaptureBuildOutput {
+		w := newLineWriter(func(line string) { r.notify(BuildOutput{Tool: t, Line: line}) })
+		defer w.Flush()
+		opts.Stderr = w
+	}
+
+	err = r.manager.Build(ctx, tmpPath, string(t), opts)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(r.FS.Rename(tmpPath, binPath))
+}
+
+func (r *repositoryImpl) BuildAll(ctx context.Context, groups ...string) error {
 	m, err := r.getManifest()
 	if err != nil {
 		return errors.WithStack(err)
 	}
 
+	tools, err := findToolsInGroups(m, groups)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer unlock()
+
+	return r.buildAll(ctx, tools)
+}
+
+func (r *repositoryImpl) buildAll(ctx context.Context, tools []Tool) error {
 	var (
 		wg   sync.WaitGroup
 		errs BuildErrors
 	)
 
-	for _, t := range m.Tools() {
+	for _, t := range tools {
 		t := t
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			_, err = r.Build(ctx, t)
+			_, err := r.build(ctx, t)
 			if err != nil {
 				errs.Append(t, err)
 			}
@@ -143,6 +268,99 @@ func (r *repositoryImpl) BuildAll(ctx context.Context) error {
 	return nil
 }
 
+func (r *repositoryImpl) Download(ctx context.Context, groups ...string) error {
+	m, err := r.getManifest()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	tools, err := findToolsInGroups(m, groups)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	d, ok := r.manager.(manager.Downloader)
+	if !ok {
+		return errors.Errorf("%s does not support downloading tools", r.managerType)
+	}
+
+	r.notify(DownloadStarted{Tools: tools})
+	start := time.Now()
+	err = d.Download(ctx, toolPackages(tools), r.Verbose)
+	r.notify(DownloadFinished{Tools: tools, Duration: time.Since(start), Err: err})
+	if err != nil {
+		return errors.Wrap(err, "failed to download tools")
+	}
+	return nil
+}
+
+func (r *repositoryImpl) Scan(ctx context.Context) (*ScanReport, error) {
+	m, err := r.getManifest()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	refs, err := NewScanner(r.FS, r.ManifestPath(), r.BinDir()).Scan(filepath.Join(r.baseDir(), "..."))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	built := func(name string) bool {
+		ok, _ := afero.Exists(r.FS, r.BinPath(name))
+		return ok
+	}
+	return newScanReport(m, refs, built), nil
+}
+
+func (r *repositoryImpl) Generate(ctx context.Context, patterns ...string) error {
+	m, err := r.getManifest()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	out, err := r.executor.Output(ctx, "go", append([]string{"list", "-f", "{{.Dir}}"}, patterns...)...)
+	if err != nil {
+		return errors.Wrap(err, "failed to list packages")
+	}
+
+	var dirs []string
+	for _, dir := range strings.Split(string(out), "\n") {
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	refs, err := NewScanner(r.FS, r.ManifestPath()).Scan(dirs...)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	tools := referencedTools(m, refs)
+	r.notify(GenerateStarted{Tools: tools})
+
+	// tools invoked by go generate can run gex, so the lock is released before it
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = r.buildAll(ctx, tools)
+	unlock()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	args := []string{"generate"}
+	if r.Verbose {
+		args = append(args, "-v")
+	}
+	args = append(args, patterns...)
+	return errors.WithStack(r.executor.Exec(ctx, "go", args...))
+}
+
 func (r *repositoryImpl) Run(ctx context.Context, name string, args ...string) error {
 	m, err := r.getManifest()
 	if err != nil {
@@ -151,15 +369,54 @@ func (r *repositoryImpl) Run(ctx context.Context, name string, args ...string) e
 
 	t, ok := m.FindTool(name)
 	if !ok {
-		return errors.Errorf("failed to find the tool %q", name)
+		return errors.WithStack(r.toolNotFound(ctx, m, name))
 	}
 
+	// the tool can run gex (e.g. via shims), so the lock is released before executing it
 	bin, err := r.Build(ctx, t)
 	if err != nil {
 		return errors.WithStack(err)
 	}
 
-	return errors.WithStack(r.executor.Exec(ctx, bin, args...))
+	r.notify(ExecStarted{Tool: t, BinPath: bin, Args: args})
+	return errors.WithStack(manager.ExecutorWithEnv(r.executor, r.ToolEnv[t.Name()]...).Exec(ctx, bin, args...))
+}
+
+func (r *repositoryImpl) sync(ctx context.Context) error {
+	r.notify(SyncStarted{})
+	start := time.Now()
+	err := r.manager.Sync(ctx, r.Verbose)
+	r.notify(SyncFinished{Duration: time.Since(start), Err: err})
+	return errors.WithStack(err)
+}
+
+func (r *repositoryImpl) notify(ev Event) {
+	if r.Observer != nil {
+		r.Observer.OnEvent(ev)
+		return
+	}
+	r.logObserver.OnEvent(ev)
+}
+
+// lock acquires the lock for operations that modify the manifest and binaries.
+// The lock is not reentrant, so it should be taken only by exported methods.
+func (r *repositoryImpl) lock(ctx context.Context) (unlock func(), err error) {
+	if r.Locker == nil {
+		return func() {}, nil
+	}
+	unlock, err = r.Locker.Lock(ctx)
+	return unlock, errors.WithStack(err)
+}
+
+// findToolsInGroups returns tools that belong to any of the groups.
+// It returns an error if some groups have no tools, since they are likely typos.
+func findToolsInGroups(m *Manifest, groups []string) ([]Tool, error) {
+	for _, g := range groups {
+		if len(m.ToolsInGroups(g)) == 0 {
+			return nil, errors.Errorf("no tools belong to the group %q", g)
+		}
+	}
+	return m.ToolsInGroups(groups...), nil
 }
 
 func (r *repositoryImpl) getManifest() (*Manifest, error) {
@@ -0,0 +1,150 @@
+package tool_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/izumin5210/execx"
+	"github.com/spf13/afero"
+
+	"github.com/izumin5210/gex/pkg/manager"
+	"github.com/izumin5210/gex/pkg/tool"
+)
+
+func TestRepository_Generate(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	obs := new(recordingObserver)
+	cfg := &tool.Config{
+		FS:           fs,
+		RootDir:      "/home/src/awesomeapp",
+		ManifestName: "tools.go",
+		BinDirName:   "bin",
+		Log:          log.New(ioutil.Discard, "", 0),
+		Observer:     obs,
+	}
+	err := tool.NewWriter(fs).Write(cfg.ManifestPath(), tool.NewManifest([]tool.Tool{
+		"github.com/golang/mock/mockgen",
+		"golang.org/x/lint/golint",
+	}, manager.TypeModules))
+	if err != nil {
+		t.Fatalf("failed to write the manifest: %v", err)
+	}
+	err = afero.WriteFile(fs, "/home/src/awesomeapp/foo/foo.go", []byte(`package foo
+
+//go:generate mockgen -source=foo.go -destination=mock/foo.go
+`), 0644)
+	if err != nil {
+		t.Fatalf("failed to write foo.go: %v", err)
+	}
+
+	var (
+		cmds [][]string
+		path string
+	)
+	fakeExec := execx.New(execx.WithFakeProcess(func(_ context.Context, cmd *exec.Cmd) error {
+		cmds = append(cmds, cmd.Args)
+		switch cmd.Args[1] {
+		case "list":
+			fmt.Fprintln(cmd.Stdout, "/home/src/awesomeapp/foo")
+		case "generate":
+			for _, kv := range cmd.Env {
+				if strings.HasPrefix(kv, "PATH=") {
+					path = strings.TrimPrefix(kv, "PATH=")
+				}
+			}
+		}
+		return nil
+	}))
+	env := manager.Environ([]string{"PATH=/usr/bin"}, cfg.BinDir())
+	executor := manager.NewExecutorWithEnv(fakeExec, ioutil.Discard, ioutil.Discard, nil, cfg.RootDir, env, cfg.Log)
+
+	repo := tool.NewRepository(executor, &fakeManager{fs: fs}, manager.TypeModules, cfg)
+	err = repo.Generate(context.Background(), "./foo/...")
+	if err != nil {
+		t.Fatalf("Generate() returned an error: %v", err)
+	}
+
+	wantCmds := [][]string{
+		{"go", "list", "-f", "{{.Dir}}", "./foo/..."},
+		{"go", "generate", "./foo/..."},
+	}
+	if diff := cmp.Diff(wantCmds, cmds); diff != "" {
+		t.Errorf("executed commands differ: (-want +got)\n%s", diff)
+	}
+	if diff := cmp.Diff([]tool.Event{tool.GenerateStarted{Tools: []tool.Tool{"github.com/golang/mock/mockgen"}}}, obs.events[:1]); diff != "" {
+		t.Errorf("received events differ: (-want +got)\n%s", diff)
+	}
+
+	for name, want := range map[string]bool{"mockgen": true, "golint": false} {
+		if got, _ := afero.Exists(fs, cfg.BinPath(name)); got != want {
+			t.Errorf("%s is built: %t, want %t", name, got, want)
+		}
+	}
+
+	if got, want := filepath.SplitList(path)[0], cfg.BinDir(); got != want {
+		t.Errorf("go generate is executed with PATH=%s, want %s at the head", path, want)
+	}
+}
+
+func TestRepository_Add(t *testing.T) {
+	cases := []struct {
+		test        string
+		managerType manager.Type
+		pkgs        []string
+		wantAdded   [][]string
+	}{
+		{
+			test:        "modules",
+			managerType: manager.TypeModules,
+			pkgs:        []string{"github.com/golang/mock/mockgen"},
+		},
+		{
+			test:        "modules with versions",
+			managerType: manager.TypeModules,
+			pkgs:        []string{"github.com/golang/mock/mockgen@v1.3.1", "golang.org/x/lint/golint"},
+			wantAdded:   [][]string{{"github.com/golang/mock/mockgen@v1.3.1", "golang.org/x/lint/golint"}},
+		},
+		{
+			test:        "gopath",
+			managerType: manager.TypeGOPATH,
+			pkgs:        []string{"github.com/golang/mock/mockgen"},
+			wantAdded:   [][]string{{"github.com/golang/mock/mockgen"}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			cfg := &tool.Config{
+				FS:           fs,
+				RootDir:      "/home/src/awesomeapp",
+				ManifestName: "tools.go",
+				BinDirName:   "bin",
+				Log:          log.New(ioutil.Discard, "", 0),
+			}
+			m := &fakeManager{fs: fs}
+
+			err := tool.NewRepository(nil, m, tc.managerType, cfg).Add(context.Background(), tc.pkgs...)
+			if err != nil {
+				t.Fatalf("Add() returned an error: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.wantAdded, m.added); diff != "" {
+				t.Errorf("Add() of the manager is called with unexpected packages: (-want +got)\n%s", diff)
+			}
+			for _, pkg := range tc.pkgs {
+				name := tool.Tool(strings.SplitN(pkg, "@", 2)[0]).Name()
+				if ok, _ := afero.Exists(fs, cfg.BinPath(name)); !ok {
+					t.Errorf("%s is not built", name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,308 @@
+package tool
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+const cmdGex = "gex"
+
+// Reference represents a command invoked from a go:generate directive.
+type Reference struct {
+	// Name is an executable name of the command.
+	Name string
+	// Package is an import path of the command. It is set only when the command is invoked with `go run`.
+	Package string
+	// Bin is true if the command is expected in the bin directory, that is invoked with gex or a path in the bin directory.
+	Bin bool
+	// Pos is a position of the directive, formatted as "file:line".
+	Pos string
+}
+
+// Scanner finds commands invoked from go:generate directives.
+type Scanner interface {
+	// Scan reads Go files in given directories. A directory ending with "/..." is walked recursively.
+	Scan(dirs ...string) ([]Reference, error)
+}
+
+// NewScanner creates a new Scanner instance. Files in ignored paths are not scanned.
+func NewScanner(fs afero.Fs, ignored ...string) Scanner {
+	ignoredSet := make(map[string]struct{}, len(ignored))
+	for _, p := range ignored {
+		ignoredSet[filepath.Clean(p)] = struct{}{}
+	}
+	return &scannerImpl{
+		fs:      fs,
+		ignored: ignoredSet,
+	}
+}
+
+type scannerImpl struct {
+	fs      afero.Fs
+	ignored map[string]struct{}
+}
+
+func (s *scannerImpl) Scan(dirs ...string) ([]Reference, error) {
+	var refs []Reference
+
+	for _, dir := range dirs {
+		recursive := strings.HasSuffix(filepath.ToSlash(dir), "/...")
+		if recursive {
+			dir = filepath.Dir(dir)
+		}
+		root := filepath.Clean(dir)
+
+		err := afero.Walk(s.fs, root, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			if _, ok := s.ignored[p]; ok {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info.IsDir() {
+				if p != root && (!recursive || skipDir(info.Name())) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if filepath.Ext(p) != ".go" {
+				return nil
+			}
+			fileRefs, err := s.scanFile(p)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			refs = append(refs, fileRefs...)
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to scan %s", dir)
+		}
+	}
+
+	return refs, nil
+}
+
+func (s *scannerImpl) scanFile(p string) ([]Reference, error) {
+	data, err := afero.ReadFile(s.fs, p)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var (
+		refs    []Reference
+		aliases = make(map[string]struct{})
+		sc      = bufio.NewScanner(bytes.NewReader(data))
+	)
+
+	for line := 1; sc.Scan(); line++ {
+		words, ok := parseGenerateDirective(sc.Text())
+		if !ok || len(words) == 0 {
+			continue
+		}
+		if words[0] == "-command" {
+			if len(words) < 3 {
+				continue
+			}
+			aliases[words[1]] = struct{}{}
+			words = words[2:]
+		} else if _, ok := aliases[words[0]]; ok {
+			continue
+		}
+		for _, ref := range parseCommand(words) {
+			ref.Pos = fmt.Sprintf("%s:%d", p, line)
+			refs = append(refs, ref)
+		}
+	}
+
+	return refs, errors.WithStack(sc.Err())
+}
+
+func skipDir(name string) bool {
+	return name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")
+}
+
+// parseGenerateDirective splits a go:generate directive into words in the same manner as `go generate`.
+func parseGenerateDirective(line string) ([]string, bool) {
+	const prefix = "//go:generate"
+	if !strings.HasPrefix(line, prefix) {
+		return nil, false
+	}
+	line = line[len(prefix):]
+	if len(line) > 0 && line[0] != ' ' && line[0] != '\t' {
+		return nil, false
+	}
+
+	var words []string
+	for {
+		line = strings.TrimLeft(line, " \t")
+		if line == "" {
+			break
+		}
+		if line[0] == '"' {
+			end := quotedWordEnd(line)
+			if end < 0 {
+				return nil, false
+			}
+			word, err := strconv.Unquote(line[:end])
+			if err != nil {
+				return nil, false
+			}
+			words = append(words, word)
+			line = line[end:]
+			continue
+		}
+		i := strings.IndexAny(line, " \t")
+		if i < 0 {
+			i = len(line)
+		}
+		words = append(words, line[:i])
+		line = line[i:]
+	}
+
+	return words, true
+}
+
+func quotedWordEnd(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// goFlagsWithValue is a set of build flags of `go run` that take a value as the next argument.
+var goFlagsWithValue = map[string]struct{}{
+	"-asmflags": {}, "-buildmode": {}, "-compiler": {}, "-exec": {}, "-gccgoflags": {}, "-gcflags": {},
+	"-installsuffix": {}, "-ldflags": {}, "-mod": {}, "-modfile": {}, "-overlay": {}, "-p": {}, "-pkgdir": {},
+	"-tags": {}, "-toolexec": {},
+}
+
+// parseCommand returns commands invoked by given words.
+// `gex <tool>` refers both gex and the tool, and `go run <pkg>` refers the package.
+func parseCommand(words []string) []Reference {
+	name := path.Base(filepath.ToSlash(words[0]))
+	args := words[1:]
+
+	switch name {
+	case cmdGex:
+		refs := []Reference{{Name: name}}
+		for _, arg := range args {
+			if !strings.HasPrefix(arg, "-") {
+				refs = append(refs, Reference{Name: arg, Bin: true})
+				break
+			}
+		}
+		return refs
+	case "go":
+		if len(args) == 0 || args[0] != "run" {
+			return nil
+		}
+		for i := 1; i < len(args); i++ {
+			arg := args[i]
+			if strings.HasPrefix(arg, "-") {
+				if _, ok := goFlagsWithValue[arg]; ok {
+					i++
+				}
+				continue
+			}
+			pkg := strings.SplitN(arg, "@", 2)[0]
+			if strings.HasPrefix(pkg, ".") || filepath.IsAbs(pkg) || strings.HasSuffix(pkg, ".go") {
+				return nil
+			}
+			return []Reference{{Name: path.Base(pkg), Package: pkg}}
+		}
+		return nil
+	default:
+		// e.g. ./bin/mockgen
+		inBin := path.Base(path.Dir(filepath.ToSlash(words[0]))) == "bin"
+		return []Reference{{Name: name, Bin: inBin}}
+	}
+}
+
+// ScanReport contains differences between tools in the manifest and commands invoked from go:generate directives.
+type ScanReport struct {
+	// Missing contains commands that are not managed in the manifest.
+	Missing []Reference
+	// Unused contains tools that are never invoked.
+	Unused []Tool
+}
+
+// Empty returns true if the manifest matches with go:generate directives.
+func (r *ScanReport) Empty() bool {
+	return len(r.Missing) == 0 && len(r.Unused) == 0
+}
+
+// newScanReport compares the manifest with the references.
+// Commands that are not Go tools, such as sh and protoc, are not reported as missing,
+// so only commands invoked with `go run`, gex or from the bin directory, and commands built in the bin directory are reported.
+func newScanReport(m *Manifest, refs []Reference, built func(name string) bool) *ScanReport {
+	report := new(ScanReport)
+	used := make(map[Tool]struct{})
+	seen := make(map[string]struct{})
+
+	for _, ref := range refs {
+		if t, ok := m.findReferencedTool(ref); ok {
+			used[t] = struct{}{}
+			continue
+		}
+		// gex itself does not have to be managed in the manifest
+		if ref.Name == cmdGex && ref.Package == "" {
+			continue
+		}
+		if ref.Package == "" && !ref.Bin && !built(ref.Name) {
+			continue
+		}
+		key := ref.Name + "\x00" + ref.Package
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		report.Missing = append(report.Missing, ref)
+	}
+
+	for _, t := range m.Tools() {
+		// gex is added by `gex init` to pin its version, and is not referred from directives in most projects
+		if t.Name() == cmdGex {
+			continue
+		}
+		if _, ok := used[t]; !ok {
+			report.Unused = append(report.Unused, t)
+		}
+	}
+
+	return report
+}
+
+// referencedTools returns tools in the manifest that are referred from given references.
+func referencedTools(m *Manifest, refs []Reference) []Tool {
+	set := make(map[Tool]struct{})
+	for _, ref := range refs {
+		if t, ok := m.findReferencedTool(ref); ok {
+			set[t] = struct{}{}
+		}
+	}
+	tools := make([]Tool, 0, len(set))
+	for _, t := range m.Tools() {
+		if _, ok := set[t]; ok {
+			tools = append(tools, t)
+		}
+	}
+	return tools
+}
@@ -0,0 +1,146 @@
+package tool_test
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+
+	"github.com/izumin5210/gex/pkg/manager"
+	"github.com/izumin5210/gex/pkg/tool"
+)
+
+func TestScanner_Scan(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	files := map[string]string{
+		"/home/src/awesomeapp/tools.go": `package tools
+
+//go:generate go build -v -o=./bin/mockgen github.com/golang/mock/mockgen
+`,
+		"/home/src/awesomeapp/foo/foo.go": `package foo
+
+//go:generate mockgen -source=foo.go -destination=mock/foo.go
+//go:generate ../bin/stringer -type=Kind
+//go:generate gex --verbose enumer -type=Status
+//go:generate go run -tags tools github.com/volatiletech/sqlboiler@v3.7.0 psql
+//go:generate go run ./internal/gen
+//go:generate -command protoc "../bin/protoc-gen-go" --go_out=.
+//go:generate protoc foo.proto
+//go:generatenot a directive
+`,
+		"/home/src/awesomeapp/foo/vendor/bar/bar.go": `package bar
+
+//go:generate vendored
+`,
+		"/home/src/awesomeapp/foo/baz/baz.go": `package baz
+
+//go:generate baz
+`,
+	}
+	for path, content := range files {
+		err := afero.WriteFile(fs, path, []byte(content), 0644)
+		if err != nil {
+			t.Fatalf("faield to write %s: %v", path, err)
+		}
+	}
+
+	cases := []struct {
+		test string
+		dirs []string
+		want []tool.Reference
+	}{
+		{
+			test: "recursive",
+			dirs: []string{"/home/src/awesomeapp/..."},
+			want: []tool.Reference{
+				{Name: "baz", Pos: "/home/src/awesomeapp/foo/baz/baz.go:3"},
+				{Name: "mockgen", Pos: "/home/src/awesomeapp/foo/foo.go:3"},
+				{Name: "stringer", Bin: true, Pos: "/home/src/awesomeapp/foo/foo.go:4"},
+				{Name: "gex", Pos: "/home/src/awesomeapp/foo/foo.go:5"},
+				{Name: "enumer", Bin: true, Pos: "/home/src/awesomeapp/foo/foo.go:5"},
+				{Name: "sqlboiler", Package: "github.com/volatiletech/sqlboiler", Pos: "/home/src/awesomeapp/foo/foo.go:6"},
+				{Name: "protoc-gen-go", Bin: true, Pos: "/home/src/awesomeapp/foo/foo.go:8"},
+			},
+		},
+		{
+			test: "non-recursive",
+			dirs: []string{"/home/src/awesomeapp/foo/baz"},
+			want: []tool.Reference{
+				{Name: "baz", Pos: "/home/src/awesomeapp/foo/baz/baz.go:3"},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			refs, err := tool.NewScanner(fs, "/home/src/awesomeapp/tools.go").Scan(tc.dirs...)
+			if err != nil {
+				t.Fatalf("Scan() returned an error: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.want, refs); diff != "" {
+				t.Errorf("references differ: (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRepository_Scan(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	cfg := &tool.Config{
+		FS:           fs,
+		RootDir:      "/home/src/awesomeapp",
+		ManifestName: "tools.go",
+		BinDirName:   "bin",
+		Log:          log.New(ioutil.Discard, "", 0),
+	}
+	err := tool.NewWriter(fs).Write(cfg.ManifestPath(), tool.NewManifest([]tool.Tool{
+		"github.com/golang/mock/mockgen",
+		"github.com/izumin5210/gex/cmd/gex",
+		"golang.org/x/tools/cmd/stringer",
+	}, manager.TypeModules))
+	if err != nil {
+		t.Fatalf("failed to write the manifest: %v", err)
+	}
+	err = afero.WriteFile(fs, "/home/src/awesomeapp/foo/foo.go", []byte(`package foo
+
+//go:generate mockgen -source=foo.go -destination=mock/foo.go
+//go:generate enumer -type=Status
+//go:generate gex enumer -type=Kind
+//go:generate sh -c "echo generated"
+//go:generate protoc --go_out=. foo.proto
+//go:generate ../bin/protoc-gen-grpc-gateway --version
+//go:generate go run github.com/volatiletech/sqlboiler psql
+//go:generate golint
+`), 0644)
+	if err != nil {
+		t.Fatalf("failed to write foo.go: %v", err)
+	}
+	// built with gex, but removed from the manifest
+	err = afero.WriteFile(fs, cfg.BinPath("golint"), []byte("golint"), 0755)
+	if err != nil {
+		t.Fatalf("failed to write a binary: %v", err)
+	}
+
+	report, err := tool.NewRepository(nil, &fakeManager{fs: fs}, manager.TypeModules, cfg).Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan() returned an error: %v", err)
+	}
+
+	want := &tool.ScanReport{
+		Missing: []tool.Reference{
+			{Name: "enumer", Bin: true, Pos: "/home/src/awesomeapp/foo/foo.go:5"},
+			{Name: "protoc-gen-grpc-gateway", Bin: true, Pos: "/home/src/awesomeapp/foo/foo.go:8"},
+			{Name: "sqlboiler", Package: "github.com/volatiletech/sqlboiler", Pos: "/home/src/awesomeapp/foo/foo.go:9"},
+			{Name: "golint", Pos: "/home/src/awesomeapp/foo/foo.go:10"},
+		},
+		Unused: []tool.Tool{"golang.org/x/tools/cmd/stringer"},
+	}
+	if diff := cmp.Diff(want, report); diff != "" {
+		t.Errorf("Scan() returned an unexpected report: (-want +got)\n%s", diff)
+	}
+}
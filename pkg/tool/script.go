@@ -0,0 +1,103 @@
+package tool
+
+import (
+	"context"
+	"io"
+	"path"
+	"path/filepath"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+
+	"github.com/izumin5210/gex/pkg/manager"
+)
+
+// ScriptFormat represents a format of scripts to build tools without gex.
+type ScriptFormat string
+
+// ScriptFormat values
+const (
+	ScriptShell ScriptFormat = "sh"
+	ScriptMake  ScriptFormat = "make"
+)
+
+// ParseScriptFormat returns a ScriptFormat from a string.
+func ParseScriptFormat(s string) (ScriptFormat, error) {
+	switch f := ScriptFormat(s); f {
+	case ScriptShell, ScriptMake:
+		return f, nil
+	}
+	return "", errors.Errorf("unknown script format %q, it should be sh or make", s)
+}
+
+func (r *repositoryImpl) ExportScript(ctx context.Context, w io.Writer, format ScriptFormat) error {
+	tmpl, ok := scriptTemplates[format]
+	if !ok {
+		return errors.Errorf("unknown script format %q", format)
+	}
+
+	m, err := r.getManifest()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	rootDir := r.baseDir()
+	data := &scriptData{
+		Commands: newBuildCommands(r.FS, rootDir, m),
+		BinDir:   filepath.ToSlash(r.BinDirName),
+		Deps:     []string{filepath.ToSlash(r.ManifestName)},
+	}
+	if b, ok := manager.Lookup(m.ManagerType()); ok {
+		for _, f := range b.LockFiles {
+			if ok, err := afero.Exists(r.FS, filepath.Join(rootDir, filepath.FromSlash(f))); err != nil {
+				return errors.WithStack(err)
+			} else if ok {
+				data.Deps = append(data.Deps, f)
+			}
+		}
+	}
+
+	err = tmpl.Execute(w, data)
+	if err != nil {
+		return errors.Wrap(err, "failed to write a script")
+	}
+	return nil
+}
+
+type scriptData struct {
+	Commands []*buildCommand
+	BinDir   string
+	// Deps contains files that decide versions of tools.
+	Deps []string
+}
+
+func (d *scriptData) BinPath(c *buildCommand) string {
+	return path.Join(d.BinDir, c.Name)
+}
+
+var scriptTemplates = map[ScriptFormat]*template.Template{
+	ScriptShell: template.Must(template.New("sh").Parse(`#!/bin/sh
+# Code generated by github.com/izumin5210/gex. DO NOT EDIT.
+# Run this script in the project root to build tools.
+
+set -eu
+{{range $c := .Commands}}
+go build {{range $c.Flags}}{{.}} {{end}}-v -o={{$.BinPath $c}} {{$c.Package}}
+{{- end}}
+`)),
+	ScriptMake: template.Must(template.New("make").Parse(`# Code generated by github.com/izumin5210/gex. DO NOT EDIT.
+# Include this file from Makefile in the project root to build tools.
+
+GEX_TOOLS :={{range $c := .Commands}} \
+	{{$.BinPath $c}}
+{{- end}}
+
+.PHONY: tools
+tools: $(GEX_TOOLS)
+{{range $c := .Commands}}
+{{$.BinPath $c}}:{{range $.Deps}} {{.}}{{end}}
+	go build {{range $c.Flags}}{{.}} {{end}}-v -o=$@ {{$c.Package}}
+{{end -}}
+`)),
+}
@@ -0,0 +1,75 @@
+package tool_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/bradleyjkemp/cupaloy/v2"
+	"github.com/spf13/afero"
+
+	"github.com/izumin5210/gex/pkg/manager"
+	"github.com/izumin5210/gex/pkg/tool"
+)
+
+func TestRepository_ExportScript(t *testing.T) {
+	cases := []struct {
+		test  string
+		typ   manager.Type
+		files []string
+	}{
+		{test: "mod", typ: manager.TypeModules, files: []string{"go.mod", "go.sum"}},
+		{test: "mod without go.sum", typ: manager.TypeModules, files: []string{"go.mod"}},
+		{test: "dep", typ: manager.TypeDep, files: []string{"Gopkg.toml", "Gopkg.lock"}},
+	}
+
+	for _, tc := range cases {
+		for _, format := range []tool.ScriptFormat{tool.ScriptShell, tool.ScriptMake} {
+			t.Run(tc.test+"/"+string(format), func(t *testing.T) {
+				fs := afero.NewMemMapFs()
+				rootDir := "/home/src/awesomeapp"
+				for _, f := range tc.files {
+					err := afero.WriteFile(fs, rootDir+"/"+f, []byte(""), 0644)
+					if err != nil {
+						t.Fatalf("failed to write %s: %v", f, err)
+					}
+				}
+
+				cfg := &tool.Config{
+					FS:           fs,
+					RootDir:      rootDir,
+					WorkingDir:   rootDir,
+					ManifestName: "tools.go",
+					BinDirName:   "bin",
+				}
+				err := tool.NewWriter(fs).Write(cfg.ManifestPath(), tool.NewManifest([]tool.Tool{
+					"github.com/golang/mock/mockgen",
+					"golang.org/x/lint/golint",
+				}, tc.typ))
+				if err != nil {
+					t.Fatalf("failed to write the manifest: %v", err)
+				}
+
+				repo := tool.NewRepository(nil, nil, tc.typ, cfg)
+				buf := new(bytes.Buffer)
+				err = repo.ExportScript(context.Background(), buf, format)
+				if err != nil {
+					t.Fatalf("ExportScript() returned an error: %v", err)
+				}
+
+				cupaloy.SnapshotT(t, buf.String())
+			})
+		}
+	}
+}
+
+func TestParseScriptFormat(t *testing.T) {
+	for _, s := range []string{"sh", "make"} {
+		if f, err := tool.ParseScriptFormat(s); err != nil || string(f) != s {
+			t.Errorf("ParseScriptFormat(%q) returned (%q, %v)", s, f, err)
+		}
+	}
+	if _, err := tool.ParseScriptFormat("ps1"); err == nil {
+		t.Error("ParseScriptFormat() should return an error for unknown formats")
+	}
+}
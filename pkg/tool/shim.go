@@ -0,0 +1,128 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+const shimHeader = "#!/bin/sh\n# Code generated by github.com/izumin5210/gex. DO NOT EDIT.\n"
+
+// gexPackage is a package of gex itself, that `gex init` adds into the manifest.
+const gexPackage = "github.com/izumin5210/gex/cmd/gex"
+
+// WriteShims writes scripts that build tools on first use and execute them into the bin directory.
+// Binaries that have already been built are moved to the location for shimmed tools.
+func (r *repositoryImpl) WriteShims(ctx context.Context) error {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer unlock()
+
+	m, err := r.getManifest()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	for _, t := range m.Tools() {
+		// a shim for gex invokes itself
+		if t.Name() == cmdGex {
+			continue
+		}
+
+		binPath := r.BinPath(t.Name())
+		if ok, err := r.isShim(binPath); err != nil {
+			return errors.WithStack(err)
+		} else if !ok {
+			if exists, err := afero.Exists(r.FS, binPath); err != nil {
+				return errors.WithStack(err)
+			} else if exists {
+				err = r.moveFile(binPath, r.ShimmedBinPath(t.Name()))
+				if err != nil {
+					return errors.Wrapf(err, "failed to move the binary of %s", t)
+				}
+			}
+		}
+
+		err = r.FS.MkdirAll(r.BinDir(), 0755)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		err = writeFile(r.FS, binPath, []byte(shimScript(t, r.GexPath)), 0755)
+		if err != nil {
+			return errors.Wrapf(err, "failed to write a shim for %s", t)
+		}
+		r.notify(ShimWritten{Tool: t, Path: binPath})
+	}
+
+	return nil
+}
+
+// binPath returns a path to an executable binary of the tool.
+// If a shim exists in the bin directory, the binary is located in the directory for shimmed tools.
+func (r *repositoryImpl) binPath(t Tool) (string, error) {
+	binPath := r.BinPath(t.Name())
+	if ok, err := r.isShim(binPath); err != nil {
+		return "", errors.WithStack(err)
+	} else if ok {
+		return r.ShimmedBinPath(t.Name()), nil
+	}
+	return binPath, nil
+}
+
+func (r *repositoryImpl) isShim(path string) (bool, error) {
+	f, err := r.FS.Open(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, errors.WithStack(err)
+	}
+	defer f.Close()
+
+	if st, err := f.Stat(); err != nil {
+		return false, errors.WithStack(err)
+	} else if st.IsDir() {
+		return false, nil
+	}
+
+	buf := make([]byte, len(shimHeader))
+	_, err = io.ReadFull(f, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return false, nil
+	} else if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	return bytes.Equal(buf, []byte(shimHeader)), nil
+}
+
+func (r *repositoryImpl) moveFile(src, dest string) error {
+	if err := r.FS.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(r.FS.Rename(src, dest))
+}
+
+// shimScript returns a script that executes the tool via gex at gexPath.
+// gex is not on PATH in most cases, so `go run` is used if gexPath is empty.
+// The tool is executed with `gex run`, since tools named like commands of gex can not be executed with `gex <tool>`.
+func shimScript(t Tool, gexPath string) string {
+	gex := "go run " + gexPackage
+	if gexPath != "" {
+		gex = ShellQuote(gexPath)
+	}
+	return shimHeader + fmt.Sprintf("exec %s run %s -- \"$@\"\n", gex, t.Name())
+}
+
+// ShellQuote quotes the string with single quotes for POSIX shells.
+func ShellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
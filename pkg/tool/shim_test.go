@@ -0,0 +1,91 @@
+package tool_test
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/spf13/afero"
+
+	"github.com/izumin5210/gex/pkg/manager"
+	"github.com/izumin5210/gex/pkg/tool"
+)
+
+func TestRepository_WriteShims(t *testing.T) {
+	cases := []struct {
+		test    string
+		gexPath string
+		want    string
+	}{
+		{
+			test:    "with gex path",
+			gexPath: "/home/user's/go/bin/gex",
+			want: `#!/bin/sh
+# Code generated by github.com/izumin5210/gex. DO NOT EDIT.
+exec '/home/user'\''s/go/bin/gex' run mockgen -- "$@"
+`,
+		},
+		{
+			test: "without gex path",
+			want: `#!/bin/sh
+# Code generated by github.com/izumin5210/gex. DO NOT EDIT.
+exec go run github.com/izumin5210/gex/cmd/gex run mockgen -- "$@"
+`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			cfg := &tool.Config{
+				FS:           fs,
+				RootDir:      "/home/src/awesomeapp",
+				ManifestName: "tools.go",
+				BinDirName:   "bin",
+				Log:          log.New(ioutil.Discard, "", 0),
+				GexPath:      tc.gexPath,
+			}
+			err := tool.NewWriter(fs).Write(cfg.ManifestPath(), tool.NewManifest([]tool.Tool{
+				"github.com/golang/mock/mockgen",
+				"github.com/izumin5210/gex/cmd/gex",
+			}, manager.TypeModules))
+			if err != nil {
+				t.Fatalf("failed to write the manifest: %v", err)
+			}
+			err = afero.WriteFile(fs, cfg.BinPath("mockgen"), []byte("built"), 0755)
+			if err != nil {
+				t.Fatalf("failed to write the binary: %v", err)
+			}
+
+			repo := tool.NewRepository(nil, &fakeManager{fs: fs}, manager.TypeModules, cfg)
+			err = repo.WriteShims(context.Background())
+			if err != nil {
+				t.Fatalf("WriteShims() returned an error: %v", err)
+			}
+
+			got, err := afero.ReadFile(fs, cfg.BinPath("mockgen"))
+			if err != nil {
+				t.Fatalf("failed to read the shim: %v", err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("the shim is\n%s\nwant\n%s", got, tc.want)
+			}
+			st, err := fs.Stat(cfg.BinPath("mockgen"))
+			if err != nil {
+				t.Fatalf("failed to stat the shim: %v", err)
+			}
+			if got, want := st.Mode().Perm(), os.FileMode(0755); got != want {
+				t.Errorf("mode of the shim is %o, want %o", got, want)
+			}
+
+			if got, err := afero.ReadFile(fs, cfg.ShimmedBinPath("mockgen")); err != nil || string(got) != "built" {
+				t.Errorf("the built binary is not moved: %q, %v", got, err)
+			}
+			if ok, _ := afero.Exists(fs, cfg.BinPath("gex")); ok {
+				t.Errorf("a shim for gex itself is written")
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package tool
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+
+	"github.com/izumin5210/gex/pkg/manager"
+)
+
+// ToolStatus represents a state of the tool in the project.
+type ToolStatus struct {
+	Tool   Tool
+	Groups []string
+	// Version is a version of the module or the project that provides the tool.
+	// It is empty if the manager does not pin versions.
+	Version string
+	BinPath string
+	// Built is true if the binary has been built.
+	Built bool
+}
+
+func (r *repositoryImpl) Status(ctx context.Context, groups ...string) ([]*ToolStatus, error) {
+	m, err := r.getManifest()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	tools, err := findToolsInGroups(m, groups)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var versions map[string]string
+	if vr, ok := r.manager.(manager.VersionReader); ok && len(tools) > 0 {
+		versions, err = vr.Versions(ctx, toolPackages(tools))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get versions of tools")
+		}
+	}
+
+	statuses := make([]*ToolStatus, 0, len(tools))
+	for _, t := range tools {
+		binPath, err := r.binPath(t)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		built, err := afero.Exists(r.FS, binPath)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		statuses = append(statuses, &ToolStatus{
+			Tool:    t,
+			Groups:  m.Groups(t),
+			Version: versions[string(t)],
+			BinPath: binPath,
+			Built:   built,
+		})
+	}
+
+	return statuses, nil
+}
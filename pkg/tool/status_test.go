@@ -0,0 +1,126 @@
+package tool_test
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+
+	"github.com/izumin5210/gex/pkg/manager"
+	"github.com/izumin5210/gex/pkg/tool"
+)
+
+func TestRepository_Status(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	cfg := &tool.Config{
+		FS:           fs,
+		RootDir:      "/home/src/awesomeapp",
+		ManifestName: "tools.go",
+		BinDirName:   "bin",
+		Log:          log.New(ioutil.Discard, "", 0),
+	}
+	m := tool.NewManifest([]tool.Tool{
+		"github.com/golang/mock/mockgen",
+		"golang.org/x/lint/golint",
+	}, manager.TypeModules)
+	m.SetGroups("golang.org/x/lint/golint", "lint")
+	err := tool.NewWriter(fs).Write(cfg.ManifestPath(), m)
+	if err != nil {
+		t.Fatalf("failed to write the manifest: %v", err)
+	}
+
+	repo := tool.NewRepository(nil, &fakeManager{
+		fs: fs,
+		versions: map[string]string{
+			"github.com/golang/mock/mockgen": "v1.3.1",
+			"golang.org/x/lint/golint":       "v0.0.0-20190930215403-16217165b5de",
+		},
+	}, manager.TypeModules, cfg)
+	ctx := context.Background()
+
+	_, err = repo.Build(ctx, "golang.org/x/lint/golint")
+	if err != nil {
+		t.Fatalf("Build() returned an error: %v", err)
+	}
+
+	cases := []struct {
+		test   string
+		groups []string
+		want   []*tool.ToolStatus
+	}{
+		{
+			test: "all",
+			want: []*tool.ToolStatus{
+				{Tool: "github.com/golang/mock/mockgen", Version: "v1.3.1", BinPath: cfg.BinPath("mockgen")},
+				{Tool: "golang.org/x/lint/golint", Groups: []string{"lint"}, Version: "v0.0.0-20190930215403-16217165b5de", BinPath: cfg.BinPath("golint"), Built: true},
+			},
+		},
+		{
+			test:   "groups",
+			groups: []string{"lint"},
+			want: []*tool.ToolStatus{
+				{Tool: "golang.org/x/lint/golint", Groups: []string{"lint"}, Version: "v0.0.0-20190930215403-16217165b5de", BinPath: cfg.BinPath("golint"), Built: true},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			got, err := repo.Status(ctx, tc.groups...)
+			if err != nil {
+				t.Fatalf("Status() returned an error: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Status() returned unexpected statuses: (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+// minimalManager implements only the required methods of manager.Interface.
+type minimalManager struct {
+	fs afero.Fs
+}
+
+func (m *minimalManager) Add(ctx context.Context, pkgs []string, verbose bool) error { return nil }
+
+func (m *minimalManager) Build(ctx context.Context, binPath, pkg string, opts manager.BuildOptions) error {
+	return afero.WriteFile(m.fs, binPath, []byte(pkg), 0755)
+}
+
+func (m *minimalManager) Sync(ctx context.Context, verbose bool) error { return nil }
+
+func TestRepository_MinimalManager(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	cfg := &tool.Config{
+		FS:           fs,
+		RootDir:      "/home/src/awesomeapp",
+		ManifestName: "tools.go",
+		BinDirName:   "bin",
+		Log:          log.New(ioutil.Discard, "", 0),
+	}
+	err := tool.NewWriter(fs).Write(cfg.ManifestPath(), tool.NewManifest([]tool.Tool{"github.com/golang/mock/mockgen"}, manager.TypeModules))
+	if err != nil {
+		t.Fatalf("failed to write the manifest: %v", err)
+	}
+	repo := tool.NewRepository(nil, &minimalManager{fs: fs}, manager.TypeModules, cfg)
+	ctx := context.Background()
+
+	// versions are empty if the manager does not implement manager.VersionReader
+	got, err := repo.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() returned an error: %v", err)
+	}
+	want := []*tool.ToolStatus{{Tool: "github.com/golang/mock/mockgen", BinPath: cfg.BinPath("mockgen")}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Status() returned unexpected statuses: (-want +got)\n%s", diff)
+	}
+
+	err = repo.Download(ctx)
+	if got, want := err, "mod does not support downloading tools"; got == nil || got.Error() != want {
+		t.Errorf("Download() returned %v, want %q", got, want)
+	}
+}
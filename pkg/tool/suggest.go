@@ -0,0 +1,91 @@
+package tool
+
+import (
+	"context"
+	"sort"
+
+	"github.com/izumin5210/gex/pkg/manager"
+)
+
+const maxSuggestions = 3
+
+// toolNotFound creates an error with names of similar tools in the manifest,
+// and packages of the commands that have the name in the dependencies.
+func (r *repositoryImpl) toolNotFound(ctx context.Context, m *Manifest, name string) *ToolNotFoundError {
+	err := &ToolNotFoundError{Name: name, Suggestions: suggestTools(name, m.Tools())}
+
+	if f, ok := r.manager.(manager.CommandFinder); ok && len(err.Suggestions) == 0 {
+		pkgs, ferr := f.FindCommands(ctx, name)
+		if ferr != nil {
+			r.notify(CommandSearchFailed{Name: name, Err: ferr})
+		}
+		err.Packages = pkgs
+	}
+
+	return err
+}
+
+// suggestTools returns names of tools that are close to name in edit distance.
+func suggestTools(name string, tools []Tool) []string {
+	type candidate struct {
+		name string
+		dist int
+	}
+
+	// allow 1 typo for each 3 characters
+	threshold := len(name)/3 + 1
+
+	var cands []candidate
+	for _, t := range tools {
+		if d := editDistance(name, t.Name()); d <= threshold {
+			cands = append(cands, candidate{name: t.Name(), dist: d})
+		}
+	}
+	sort.SliceStable(cands, func(i, j int) bool {
+		if cands[i].dist != cands[j].dist {
+			return cands[i].dist < cands[j].dist
+		}
+		return cands[i].name < cands[j].name
+	})
+
+	var names []string
+	for i, c := range cands {
+		if i == maxSuggestions {
+			break
+		}
+		names = append(names, c.name)
+	}
+	return names
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	prev := make([]int, len(t)+1)
+	curr := make([]int, len(t)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		curr[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			curr[j] = min3(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(t)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,114 @@
+package tool_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+
+	"github.com/izumin5210/gex/pkg/manager"
+	"github.com/izumin5210/gex/pkg/tool"
+)
+
+type fakeCommandFinder struct {
+	manager.Interface
+	commands map[string][]string
+	err      error
+}
+
+func (f *fakeCommandFinder) FindCommands(ctx context.Context, name string) ([]string, error) {
+	return f.commands[name], f.err
+}
+
+func TestRepository_Run_NotFound(t *testing.T) {
+	searchErr := errors.New("go: updates to go.mod needed")
+
+	cases := []struct {
+		test       string
+		name       string
+		findErr    error
+		wantErr    *tool.ToolNotFoundError
+		wantMsg    string
+		wantEvents []tool.Event
+	}{
+		{
+			test:    "typo",
+			name:    "mokgen",
+			wantErr: &tool.ToolNotFoundError{Name: "mokgen", Suggestions: []string{"mockgen"}},
+			wantMsg: `failed to find the tool "mokgen", did you mean mockgen?`,
+		},
+		{
+			test:    "multiple suggestions",
+			name:    "protoc-gen-gox",
+			wantErr: &tool.ToolNotFoundError{Name: "protoc-gen-gox", Suggestions: []string{"protoc-gen-go", "protoc-gen-gogo"}},
+			wantMsg: `failed to find the tool "protoc-gen-gox", did you mean one of protoc-gen-go, protoc-gen-gogo?`,
+		},
+		{
+			test:    "found in dependencies",
+			name:    "stringer",
+			wantErr: &tool.ToolNotFoundError{Name: "stringer", Packages: []string{"golang.org/x/tools/cmd/stringer"}},
+			wantMsg: "failed to find the tool \"stringer\", run `gex --add golang.org/x/tools/cmd/stringer` to add it",
+		},
+		{
+			test:    "not found",
+			name:    "reviewdog",
+			wantErr: &tool.ToolNotFoundError{Name: "reviewdog"},
+			wantMsg: `failed to find the tool "reviewdog"`,
+		},
+		{
+			test:       "search failure",
+			name:       "reviewdog",
+			findErr:    searchErr,
+			wantErr:    &tool.ToolNotFoundError{Name: "reviewdog"},
+			wantMsg:    `failed to find the tool "reviewdog"`,
+			wantEvents: []tool.Event{tool.CommandSearchFailed{Name: "reviewdog", Err: searchErr}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			obs := new(recordingObserver)
+			cfg := &tool.Config{
+				FS:           fs,
+				RootDir:      "/home/src/awesomeapp",
+				ManifestName: "tools.go",
+				BinDirName:   "bin",
+				Observer:     obs,
+			}
+			err := tool.NewWriter(fs).Write(cfg.ManifestPath(), tool.NewManifest([]tool.Tool{
+				"github.com/golang/mock/mockgen",
+				"github.com/golang/protobuf/protoc-gen-go",
+				"github.com/gogo/protobuf/protoc-gen-gogo",
+				"golang.org/x/lint/golint",
+			}, manager.TypeModules))
+			if err != nil {
+				t.Fatalf("failed to write the manifest: %v", err)
+			}
+
+			finder := &fakeCommandFinder{commands: map[string][]string{
+				"stringer": {"golang.org/x/tools/cmd/stringer"},
+			}, err: tc.findErr}
+			repo := tool.NewRepository(nil, finder, manager.TypeModules, cfg)
+
+			err = repo.Run(context.Background(), tc.name)
+
+			nfErr, ok := errors.Cause(err).(*tool.ToolNotFoundError)
+			if !ok {
+				t.Fatalf("Run() should return *ToolNotFoundError, but returned %v", err)
+			}
+			if diff := cmp.Diff(tc.wantErr, nfErr); diff != "" {
+				t.Errorf("Run() returned unexpected error (-want, +got):\n%s", diff)
+			}
+			if got := nfErr.Error(); got != tc.wantMsg {
+				t.Errorf("Error() returned %q, want %q", got, tc.wantMsg)
+			}
+			if diff := cmp.Diff(tc.wantEvents, obs.events, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("received events differ: (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package tool_test
+
+import (
+	"testing"
+
+	"github.com/izumin5210/gex/pkg/manager"
+)
+
+// Packages of backends are not imported in this package,
+// so hints of the built-in types should be available without them.
+func TestType_Builtin(t *testing.T) {
+	cases := []struct {
+		typ    manager.Type
+		name   string
+		vendor bool
+	}{
+		{typ: manager.TypeModules, name: "mod"},
+		{typ: manager.TypeDep, name: "dep", vendor: true},
+		{typ: manager.TypeGOPATH, name: "gopath"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got, want := tc.typ.String(), tc.name; got != want {
+				t.Errorf("String() returned %q, want %q", got, want)
+			}
+			if got, want := tc.typ.Vendor(), tc.vendor; got != want {
+				t.Errorf("Vendor() returned %t, want %t", got, want)
+			}
+			if typ, err := manager.ParseType(tc.name); err != nil {
+				t.Errorf("ParseType() returned an error: %v", err)
+			} else if typ != tc.typ {
+				t.Errorf("ParseType() returned %v, want %v", typ, tc.typ)
+			}
+		})
+	}
+
+	if _, err := manager.New(manager.TypeDep, &manager.Options{}); err == nil {
+		t.Error("New() should return an error when the backend is not imported")
+	}
+}
@@ -2,10 +2,20 @@ package tool
 
 import (
 	"bytes"
-	"html/template"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/afero"
+
+	"github.com/izumin5210/gex/pkg/manager"
 )
 
 // Writer creates a tool file to manage tool dependencies.
@@ -25,37 +35,183 @@ type writerImpl struct {
 }
 
 func (w *writerImpl) Write(path string, m *Manifest) error {
-	buf := new(bytes.Buffer)
-	err := toolsGoTemplate.Execute(buf, m)
+	dir := filepath.Dir(path)
+	cmds := newBuildCommands(w.fs, dir, m)
+	legacyBuildTag := needsLegacyBuildTag(w.fs, dir)
+
+	var (
+		data []byte
+		err  error
+	)
+	if src, rerr := afero.ReadFile(w.fs, path); rerr == nil && len(bytes.TrimSpace(src)) > 0 {
+		data, err = editManifest(src, m, cmds, legacyBuildTag)
+		if err != nil {
+			return errors.Wrapf(err, "failed to update %s", path)
+		}
+	} else {
+		data, err = renderManifest(m, cmds, legacyBuildTag)
+		if err != nil {
+			return errors.Wrap(err, "failed to create a manifest file")
+		}
+	}
+
+	err = verifyManifest(data, m)
 	if err != nil {
-		return errors.Wrap(err, "failed to create a manifest file")
+		return errors.Wrapf(err, "generated %s is broken", path)
 	}
-	err = afero.WriteFile(w.fs, path, buf.Bytes(), 0644)
+
+	err = writeFile(w.fs, path, data, 0644)
 	if err != nil {
 		return errors.Wrap(err, "failed to write a manifest file")
 	}
 	return nil
 }
 
+func renderManifest(m *Manifest, cmds []*buildCommand, legacyBuildTag bool) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	err := toolsGoTemplate.Execute(buf, &templateData{Manifest: m, Commands: cmds, LegacyBuildTag: legacyBuildTag})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	data, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to format the manifest file")
+	}
+	return data, nil
+}
+
+// verifyManifest checks that the generated manifest file is parsed into the same tools as the manifest.
+func verifyManifest(data []byte, m *Manifest) error {
+	f, err := parser.ParseFile(token.NewFileSet(), "", data, parser.ImportsOnly)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	want := make(map[Tool]bool)
+	for _, t := range m.Tools() {
+		want[t] = true
+	}
+	for _, s := range f.Imports {
+		pkg, err := strconv.Unquote(s.Path.Value)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if !want[Tool(pkg)] {
+			return errors.Errorf("unexpected import %s", s.Path.Value)
+		}
+		delete(want, Tool(pkg))
+	}
+	if len(want) > 0 {
+		missing := make([]string, 0, len(want))
+		for t := range want {
+			missing = append(missing, string(t))
+		}
+		sort.Strings(missing)
+		return errors.Errorf("%s is not imported", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+var goDirectivePattern = regexp.MustCompile(`(?m)^go\s+1\.(\d+)(?:\.\d+)?\s*$`)
+
+// needsLegacyBuildTag returns true unless go.mod in the directory declares Go 1.17 or later,
+// that supports `//go:build` constraints.
+func needsLegacyBuildTag(fs afero.Fs, dir string) bool {
+	data, err := afero.ReadFile(fs, filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return true
+	}
+	m := goDirectivePattern.FindSubmatch(data)
+	if m == nil {
+		return true
+	}
+	minor, err := strconv.Atoi(string(m[1]))
+	return err != nil || minor < 17
+}
+
+type templateData struct {
+	*Manifest
+	Commands []*buildCommand
+	// LegacyBuildTag is true if `// +build` constraint is required in addition to `//go:build`.
+	LegacyBuildTag bool
+}
+
+// ImportSpec returns an import spec of the tool.
+func (d *templateData) ImportSpec(t Tool) string {
+	return importSpecLine(t, d.Groups(t))
+}
+
+// buildCommand represents a `go build` command that builds a tool into the bin directory.
+type buildCommand struct {
+	// Name is an executable name of the tool.
+	Name string
+	// Package is a package path given to `go build`, prefixed with "./vendor/" if the manager vendors tools.
+	Package string
+	// Flags contains additional flags for `go build` given by the manager backend.
+	Flags []string
+	// Alias is a name of the command defined with `//go:generate -command` for grouped tools.
+	// It contains names of the groups so that `go generate -run ':<group>:'` selects the directives.
+	Alias string
+}
+
+// Directives returns `//go:generate` directives to build the tool.
+func (c *buildCommand) Directives() []string {
+	args := append(append([]string{}, c.Flags...), "-v", "-o=./bin/"+c.Name, c.Package)
+	if c.Alias == "" {
+		return []string{"//go:generate go build " + strings.Join(args, " ")}
+	}
+	return []string{
+		"//go:generate -command " + c.Alias + " go build",
+		"//go:generate " + c.Alias + " " + strings.Join(args, " "),
+	}
+}
+
+func newBuildCommands(fs afero.Fs, rootDir string, m *Manifest) []*buildCommand {
+	var flags []string
+	b, ok := manager.Lookup(m.ManagerType())
+	if ok && b.BuildFlags != nil {
+		flags = b.BuildFlags(fs, rootDir)
+	}
+
+	cmds := make([]*buildCommand, 0, len(m.Tools()))
+	for _, t := range m.Tools() {
+		cmd := &buildCommand{Name: t.Name(), Package: string(t), Flags: flags}
+		if gs := m.Groups(t); len(gs) > 0 {
+			cmd.Alias = "gex:" + strings.Join(gs, ":") + ":" + t.Name()
+		}
+		if ok && b.Vendor {
+			cmd.Package = "./vendor/" + cmd.Package
+		}
+		cmds = append(cmds, cmd)
+	}
+	return cmds
+}
+
 var (
 	toolsGoTemplate = template.Must(template.New("tools.go").Parse(`// Code generated by github.com/izumin5210/gex. DO NOT EDIT.
 
+//go:build tools
+{{- if .LegacyBuildTag}}
 // +build tools
+{{- end}}
 
 package tools
 
 // tool dependencies
 import (
 {{- range $t := .Tools}}
-	_ "{{$t}}"
+	{{$.ImportSpec $t}}
 {{- end}}
 )
 
 // If you want to use tools, please run the following command:
 //  go generate ./tools.go
 //
-{{- range $t := .Tools}}
-//go:generate go build -v -o=./bin/{{$t.Name}} {{if $.ManagerType.Vendor}}./vendor/{{end}}{{$t}}
+{{- range $c := .Commands}}
+{{- range $c.Directives}}
+{{.}}
+{{- end}}
 {{- end}}
 `))
 )
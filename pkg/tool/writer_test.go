@@ -11,18 +11,56 @@ import (
 )
 
 func TestWriter_Write(t *testing.T) {
-	fs := afero.NewMemMapFs()
-	writer := tool.NewWriter(fs)
+	cases := []struct {
+		test      string
+		typ       manager.Type
+		modVendor bool
+		goMod     string
+		groups    map[tool.Tool][]string
+	}{
+		{test: "mod", typ: manager.TypeModules},
+		{test: "mod with go 1.16", typ: manager.TypeModules, goMod: "module awesomeapp\n\ngo 1.16\n"},
+		{test: "mod with go 1.17", typ: manager.TypeModules, goMod: "module awesomeapp\n\ngo 1.17\n"},
+		{test: "mod with go 1.21.0", typ: manager.TypeModules, goMod: "module awesomeapp\n\ngo 1.21.0\n"},
+		{
+			test: "mod with groups",
+			typ:  manager.TypeModules,
+			groups: map[tool.Tool][]string{
+				"github.com/gogo/protobuf/protoc-gen-gogofast": {"codegen"},
+				"github.com/volatiletech/sqlboiler":            {"release", "codegen"},
+			},
+		},
+		{test: "mod with vendor", typ: manager.TypeModules, modVendor: true},
+		{test: "dep", typ: manager.TypeDep},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			writer := tool.NewWriter(fs)
+			if tc.goMod != "" {
+				err := afero.WriteFile(fs, "/home/src/awesomeapp/go.mod", []byte(tc.goMod), 0644)
+				if err != nil {
+					t.Fatalf("failed to write go.mod: %v", err)
+				}
+			}
+			if tc.modVendor {
+				err := afero.WriteFile(fs, "/home/src/awesomeapp/vendor/modules.txt", []byte(""), 0644)
+				if err != nil {
+					t.Fatalf("failed to write modules.txt: %v", err)
+				}
+			}
 
-	for _, typ := range []manager.Type{manager.TypeModules, manager.TypeDep} {
-		t.Run(typ.String(), func(t *testing.T) {
 			in := tool.NewManifest([]tool.Tool{
 				"github.com/gogo/protobuf/protoc-gen-gogofast",
 				"github.com/grpc-ecosystem/grpc-gateway/protoc-gen-grpc-gateway",
 				"github.com/grpc-ecosystem/grpc-gateway/protoc-gen-swagger",
 				"github.com/volatiletech/sqlboiler",
 				"github.com/volatiletech/sqlboiler/drivers/sqlboiler-psql",
-			}, typ)
+			}, tc.typ)
+			for t, gs := range tc.groups {
+				in.SetGroups(t, gs...)
+			}
 			path := "/home/src/awesomeapp/tools"
 
 			err := writer.Write(path, in)
@@ -41,3 +79,194 @@ func TestWriter_Write(t *testing.T) {
 		})
 	}
 }
+
+func TestWriter_Write_Update(t *testing.T) {
+	t.Run("generated file", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		writer := tool.NewWriter(fs)
+
+		path := "/home/src/awesomeapp/tools.go"
+		err := writer.Write(path, tool.NewManifest([]tool.Tool{
+			"github.com/golang/mock/mockgen",
+			"golang.org/x/lint/golint",
+		}, manager.TypeModules))
+		if err != nil {
+			t.Fatalf("Write() returned an error: %v", err)
+		}
+
+		m := tool.NewManifest([]tool.Tool{
+			"github.com/golang/mock/mockgen",
+			"github.com/golangci/golangci-lint/cmd/golangci-lint",
+			"golang.org/x/tools/cmd/stringer",
+		}, manager.TypeModules)
+		m.SetGroups("github.com/golangci/golangci-lint/cmd/golangci-lint", "lint")
+
+		err = writer.Write(path, m)
+		if err != nil {
+			t.Fatalf("Write() returned an error: %v", err)
+		}
+
+		data, err := afero.ReadFile(fs, path)
+		if err != nil {
+			t.Fatalf("faield to read %s: %v", path, err)
+		}
+
+		cupaloy.SnapshotT(t, string(data))
+	})
+
+	t.Run("legacy build constraint", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		writer := tool.NewWriter(fs)
+
+		path := "/home/src/awesomeapp/tools.go"
+		err := writer.Write(path, tool.NewManifest([]tool.Tool{"github.com/golang/mock/mockgen"}, manager.TypeModules))
+		if err != nil {
+			t.Fatalf("Write() returned an error: %v", err)
+		}
+
+		// upgrade the go directive
+		err = afero.WriteFile(fs, "/home/src/awesomeapp/go.mod", []byte("module awesomeapp\n\ngo 1.17\n"), 0644)
+		if err != nil {
+			t.Fatalf("failed to write go.mod: %v", err)
+		}
+
+		err = writer.Write(path, tool.NewManifest([]tool.Tool{"github.com/golang/mock/mockgen", "golang.org/x/lint/golint"}, manager.TypeModules))
+		if err != nil {
+			t.Fatalf("Write() returned an error: %v", err)
+		}
+
+		data, err := afero.ReadFile(fs, path)
+		if err != nil {
+			t.Fatalf("faield to read %s: %v", path, err)
+		}
+
+		cupaloy.SnapshotT(t, string(data))
+	})
+
+	t.Run("hand-written file", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		writer := tool.NewWriter(fs)
+
+		path := "/home/src/awesomeapp/tools.go"
+		src := `// +build tools
+
+// Package tools manages development tools.
+package tools
+
+import (
+	// mockgen generates mocks for interfaces.
+	_ "github.com/golang/mock/mockgen"
+	// gex:group=lint
+	_ "golang.org/x/lint/golint"
+	_ "github.com/golangci/golangci-lint/cmd/golangci-lint" // gex:group=lint,ci
+)
+
+// Version is a version of tools.
+const Version = "1"
+
+//go:generate echo "build tools"
+//go:generate go build -v -o=./bin/mockgen github.com/golang/mock/mockgen
+//go:generate -command gex:lint:golint go build
+//go:generate gex:lint:golint -v -o=./bin/golint golang.org/x/lint/golint
+//go:generate go build -v -o=./bin/app ./cmd/app
+`
+		err := afero.WriteFile(fs, path, []byte(src), 0644)
+		if err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+
+		m, err := tool.NewParser(fs, manager.TypeModules).Parse(path)
+		if err != nil {
+			t.Fatalf("Parse() returned an error: %v", err)
+		}
+		m.RemoveTool("golang.org/x/lint/golint")
+		m.AddTool("golang.org/x/tools/cmd/stringer")
+		m.SetGroups("golang.org/x/tools/cmd/stringer", "codegen")
+		m.SetGroups("github.com/golang/mock/mockgen", "codegen")
+		m.SetGroups("github.com/golangci/golangci-lint/cmd/golangci-lint", "lint")
+
+		err = writer.Write(path, m)
+		if err != nil {
+			t.Fatalf("Write() returned an error: %v", err)
+		}
+
+		data, err := afero.ReadFile(fs, path)
+		if err != nil {
+			t.Fatalf("faield to read %s: %v", path, err)
+		}
+
+		cupaloy.SnapshotT(t, string(data))
+	})
+
+	t.Run("single-line import", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		writer := tool.NewWriter(fs)
+
+		path := "/home/src/awesomeapp/tools.go"
+		src := `// +build tools
+
+package tools
+
+import _ "github.com/golang/mock/mockgen" // mocks
+
+//go:generate go build -v -o=./bin/mockgen github.com/golang/mock/mockgen
+`
+		err := afero.WriteFile(fs, path, []byte(src), 0644)
+		if err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+
+		// replace the tool with the one of the same name, and add another tool
+		m := tool.NewManifest([]tool.Tool{
+			"go.uber.org/mock/mockgen",
+			"golang.org/x/tools/cmd/stringer",
+		}, manager.TypeModules)
+
+		err = writer.Write(path, m)
+		if err != nil {
+			t.Fatalf("Write() returned an error: %v", err)
+		}
+
+		data, err := afero.ReadFile(fs, path)
+		if err != nil {
+			t.Fatalf("faield to read %s: %v", path, err)
+		}
+
+		cupaloy.SnapshotT(t, string(data))
+	})
+
+	t.Run("single-line import kept", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		writer := tool.NewWriter(fs)
+
+		path := "/home/src/awesomeapp/tools.go"
+		src := `// +build tools
+
+package tools
+
+import _ "github.com/golang/mock/mockgen" // mocks
+`
+		err := afero.WriteFile(fs, path, []byte(src), 0644)
+		if err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+
+		m := tool.NewManifest([]tool.Tool{
+			"github.com/golang/mock/mockgen",
+			"golang.org/x/tools/cmd/stringer",
+		}, manager.TypeModules)
+		m.SetGroups("github.com/golang/mock/mockgen", "codegen")
+
+		err = writer.Write(path, m)
+		if err != nil {
+			t.Fatalf("Write() returned an error: %v", err)
+		}
+
+		data, err := afero.ReadFile(fs, path)
+		if err != nil {
+			t.Fatalf("faield to read %s: %v", path, err)
+		}
+
+		cupaloy.SnapshotT(t, string(data))
+	})
+}
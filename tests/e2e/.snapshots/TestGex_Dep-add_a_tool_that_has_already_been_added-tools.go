@@ -1,5 +1,6 @@
 // Code generated by github.com/izumin5210/gex. DO NOT EDIT.
 
+//go:build tools
 // +build tools
 
 package tools
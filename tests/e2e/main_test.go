@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -100,4 +101,54 @@ func invokeE2ETest(t *testing.T, tm TestMode) {
 		tc.ExecCmd(t, "go", "generate", "tools.go")
 		tc.CheckBinaries(t, []string{"protoc-gen-grpc-gateway", "mockgen", "golint", "protoc-gen-swagger", "protoc-gen-gogo", "protoc-gen-gogofast", "gex"})
 	})
+
+	t.Run("list tools in JSON", func(t *testing.T) {
+		tc.SnapshotJSON(t, 0, gexCmd, "--format", "json", "--list")
+	})
+
+	t.Run("list tools with the subcommand in JSON", func(t *testing.T) {
+		tc.SnapshotJSON(t, 0, gexCmd, "--format", "json", "list")
+	})
+
+	t.Run("build tools in JSON", func(t *testing.T) {
+		tc.SnapshotJSON(t, 0, gexCmd, "--format", "json", "--build")
+	})
+
+	t.Run("build tools with the subcommand in JSON", func(t *testing.T) {
+		tc.SnapshotJSON(t, 0, gexCmd, "--format", "json", "build")
+	})
+
+	t.Run("keep output of other commands in JSON", func(t *testing.T) {
+		var textOutW, jsonOutW, runOutW bytes.Buffer
+		tc.ExecCmdWithOut(t, gexCmd, []string{"version"}, &textOutW, ioutil.Discard)
+		tc.ExecCmdWithOut(t, gexCmd, []string{"--format", "json", "version"}, &jsonOutW, ioutil.Discard)
+		if got, want := jsonOutW.String(), textOutW.String(); got != want {
+			t.Errorf("`gex --format json version` prints %q, want %q", got, want)
+		}
+
+		tc.ExecCmdWithOut(t, gexCmd, []string{"--format", "json", "run", "gex", "--", "--version"}, &runOutW, ioutil.Discard)
+		if got, want := runOutW.String(), "0.5.1"; !strings.Contains(got, want) || strings.Contains(got, "{") {
+			t.Errorf("`gex --format json run gex -- --version` prints %q into stdout, want only the version %q", got, want)
+		}
+	})
+
+	t.Run("run a tool with the subcommand and the alias", func(t *testing.T) {
+		var subOutW, subErrW, aliasOutW, aliasErrW bytes.Buffer
+		subCode := tc.ExecCmdWithExitCode(t, gexCmd, []string{"run", "mockgen", "--", "--help"}, &subOutW, &subErrW)
+		aliasCode := tc.ExecCmdWithExitCode(t, gexCmd, []string{"mockgen", "--help"}, &aliasOutW, &aliasErrW)
+
+		if subCode != aliasCode {
+			t.Errorf("`gex mockgen` exits with %d, want %d", aliasCode, subCode)
+		}
+		if got, want := aliasOutW.String()+aliasErrW.String(), subOutW.String()+subErrW.String(); got != want {
+			t.Errorf("`gex mockgen` prints %q, want %q", got, want)
+		}
+		if !strings.Contains(subOutW.String()+subErrW.String(), "mockgen") {
+			t.Errorf("`gex run mockgen` should print the help of mockgen, got %q", subOutW.String()+subErrW.String())
+		}
+	})
+
+	t.Run("run an unknown tool in JSON", func(t *testing.T) {
+		tc.SnapshotJSON(t, 3, gexCmd, "--format", "json", "nonexistent")
+	})
 }
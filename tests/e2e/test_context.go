@@ -1,12 +1,14 @@
 package main
 
 import (
+	"bytes"
 	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"runtime"
 	"sort"
 	"strings"
@@ -97,12 +99,32 @@ func (tc *TestContext) SnapshotManifest(t *testing.T) {
 	})
 }
 
+var (
+	jsonVersionPattern  = regexp.MustCompile(`"version": "[^"]+"`)
+	jsonDurationPattern = regexp.MustCompile(`"duration_ms": \d+`)
+)
+
+// SnapshotJSON executes the command that prints a JSON document, and takes a snapshot of the document.
+// The root directory and versions of tools are replaced with placeholders since they differ in each run.
+func (tc *TestContext) SnapshotJSON(t *testing.T, wantExitCode int, name string, args ...string) {
+	t.Helper()
+	var outW bytes.Buffer
+	if got, want := tc.ExecCmdWithExitCode(t, name, args, &outW, NewTestWriter(t)), wantExitCode; got != want {
+		t.Errorf("exit code is %d, want %d", got, want)
+	}
+	out := strings.Replace(outW.String(), tc.rootDir(), "$ROOT", -1)
+	out = jsonVersionPattern.ReplaceAllString(out, `"version": "$$VERSION"`)
+	out = jsonDurationPattern.ReplaceAllString(out, `"duration_ms": 0`)
+	cupaloy.SnapshotT(t, out)
+}
+
 func (tc *TestContext) CheckBinaries(t *testing.T, wantBins []string) {
 	files, err := ioutil.ReadDir(tc.binDir())
 	tc.checkErr(t, err)
 	var gotBins []string
 	for _, f := range files {
-		if f.IsDir() {
+		// skip directories and hidden files such as the lock file
+		if f.IsDir() || strings.HasPrefix(f.Name(), ".") {
 			continue
 		}
 		gotBins = append(gotBins, f.Name())
@@ -127,12 +149,26 @@ func (tc *TestContext) ExecCmd(t *testing.T, name string, args ...string) {
 }
 
 func (tc *TestContext) ExecCmdWithOut(t *testing.T, name string, args []string, outW, errW io.Writer) {
+	tc.checkErr(t, tc.command(name, args, outW, errW).Run())
+}
+
+// ExecCmdWithExitCode executes the command and returns its exit code instead of failing the test.
+func (tc *TestContext) ExecCmdWithExitCode(t *testing.T, name string, args []string, outW, errW io.Writer) int {
+	err := tc.command(name, args, outW, errW).Run()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return exitErr.ExitCode()
+	}
+	tc.checkErr(t, err)
+	return 0
+}
+
+func (tc *TestContext) command(name string, args []string, outW, errW io.Writer) *exec.Cmd {
 	cmd := exec.Command(name, args...)
 	cmd.Dir = tc.rootDir()
 	cmd.Env = tc.environ()
 	cmd.Stdout = outW
 	cmd.Stderr = errW
-	tc.checkErr(t, cmd.Run())
+	return cmd
 }
 
 func (tc *TestContext) checkErr(t *testing.T, err error) {